@@ -0,0 +1,144 @@
+// Command purell normalizes URLs read line by line from the files given as
+// arguments, or from stdin if there are none, and writes them to stdout.
+//
+// Usage:
+//
+//	purell [--flags names] [file ...]
+//
+// The normalization flags are given as a comma-separated list of names,
+// e.g. --flags safe,sort-query,remove-fragment, as parsed by
+// purell.ParseFlags. They default to "safe". Blank lines are skipped. Lines
+// that can't be parsed, or that are longer than 1 MiB, are reported to
+// stderr with their line number, and make purell exit with status 1.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/purell"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns its exit status: 0 on success, 1 if
+// some lines could not be normalized, 2 on usage or I/O errors.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("purell", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	names := fs.String("flags", "safe", "comma-separated `names` of the normalization flags, see purell.ParseFlags")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: purell [--flags names] [file ...]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	f, err := purell.ParseFlags(*names)
+	if err == nil {
+		err = purell.Validate(f)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	w := bufio.NewWriter(stdout)
+	status := normalizeAll(w, stderr, stdin, fs.Args(), f)
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(stderr, "purell: %s\n", err)
+		return 2
+	}
+	return status
+}
+
+// normalizeAll normalizes the files, or stdin if there are none, and
+// returns the exit status.
+func normalizeAll(w, stderr io.Writer, stdin io.Reader, files []string, f purell.NormalizationFlags) int {
+	var failed int
+	if len(files) == 0 {
+		n, err := normalize(w, stderr, stdin, "stdin", f)
+		if err != nil {
+			fmt.Fprintf(stderr, "purell: %s\n", err)
+			return 2
+		}
+		failed += n
+	}
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "purell: %s\n", err)
+			return 2
+		}
+		n, err := normalize(w, stderr, file, name, f)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(stderr, "purell: %s\n", err)
+			return 2
+		}
+		failed += n
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// maxLineSize is the maximum length of a line, longer lines are reported
+// as errors.
+const maxLineSize = 1 << 20
+
+// normalize writes the normalized URL of each line of r to w, and reports
+// the lines that can't be parsed or are too long to stderr. It returns the
+// number of such lines.
+func normalize(w, stderr io.Writer, r io.Reader, name string, f purell.NormalizationFlags) (int, error) {
+	var failed int
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		s, tooLong, err := readLine(br)
+		if err != nil && err != io.EOF {
+			return failed, fmt.Errorf("%s: %w", name, err)
+		}
+		if tooLong {
+			fmt.Fprintf(stderr, "%s:%d: line too long\n", name, line)
+			failed++
+		} else if u := strings.TrimSpace(s); u != "" {
+			normalized, nerr := purell.NormalizeURLString(u, f)
+			if nerr != nil {
+				fmt.Fprintf(stderr, "%s:%d: %s\n", name, line, nerr)
+				failed++
+			} else if _, werr := fmt.Fprintln(w, normalized); werr != nil {
+				return failed, werr
+			}
+		}
+		if err == io.EOF {
+			return failed, nil
+		}
+	}
+}
+
+// readLine reads the next line, including its newline. If the line is
+// longer than maxLineSize, it is skipped and tooLong is true. The error is
+// io.EOF for the last line.
+func readLine(br *bufio.Reader) (line string, tooLong bool, err error) {
+	var buf []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if len(buf)+len(chunk) > maxLineSize {
+			tooLong, buf = true, nil
+		} else if !tooLong {
+			buf = append(buf, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			return string(buf), tooLong, err
+		}
+	}
+}
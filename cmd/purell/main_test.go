@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	testcases := []struct {
+		nm     string
+		args   []string
+		stdin  string
+		stdout string
+		stderr string
+		status int
+	}{
+		{
+			"DefaultFlags",
+			nil,
+			"HTTP://Host:80/a/\n",
+			"http://host/a/\n",
+			"",
+			0,
+		},
+		{
+			"NamedFlags",
+			[]string{"--flags", "safe,sort-query,remove-fragment"},
+			"http://host/path?b=2&a=1#frag\n  http://HOST/  \n\n",
+			"http://host/path?a=1&b=2\nhttp://host/\n",
+			"",
+			0,
+		},
+		{
+			"ConvenienceSet",
+			[]string{"-flags=usually-safe-greedy"},
+			"http://host/a/./b/\n",
+			"http://host/a/b\n",
+			"",
+			0,
+		},
+		{
+			"Subtraction",
+			[]string{"--flags", "usually-safe-greedy|-remove-trailing-slash"},
+			"http://host/a/./b/\n",
+			"http://host/a/b/\n",
+			"",
+			0,
+		},
+		{
+			"InvalidLines",
+			nil,
+			"http://host/\nhttp://[::1\n\nhttp://h/%zz\nhttp://other/\n",
+			"http://host/\nhttp://other/\n",
+			"stdin:2: parse \"http://[::1\": missing ']' in host\nstdin:4: parse \"http://h/%zz\": invalid URL escape \"%zz\"\n",
+			1,
+		},
+		{
+			"LongLine",
+			nil,
+			"http://host/" + strings.Repeat("a", maxLineSize) + "\nhttp://other/\nhttp://last/" + strings.Repeat("b", maxLineSize-len("http://last/")),
+			"http://other/\nhttp://last/" + strings.Repeat("b", maxLineSize-len("http://last/")) + "\n",
+			"stdin:1: line too long\n",
+			1,
+		},
+		{
+			"NoTrailingNewline",
+			nil,
+			"http://HOST/a\r\nhttp://HOST/b",
+			"http://host/a\nhttp://host/b\n",
+			"",
+			0,
+		},
+		{
+			"UnknownFlagName",
+			[]string{"--flags", "safe,unknown"},
+			"http://host/\n",
+			"",
+			"purell: unknown normalization flag \"unknown\"\n",
+			2,
+		},
+		{
+			"ConflictingFlags",
+			[]string{"--flags", "usually-safe-greedy,add-trailing-slash"},
+			"http://host/\n",
+			"",
+			"purell: conflicting normalization flags remove-trailing-slash and add-trailing-slash\n",
+			2,
+		},
+	}
+
+	for _, tc := range testcases {
+		var stdout, stderr bytes.Buffer
+		status := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+		if status != tc.status {
+			t.Errorf("%s - FAIL expected status %d, got %d", tc.nm, tc.status, status)
+		}
+		if got := stdout.String(); got != tc.stdout {
+			t.Errorf("%s - FAIL expected stdout '%s', got '%s'", tc.nm, tc.stdout, got)
+		}
+		if got := stderr.String(); got != tc.stderr {
+			t.Errorf("%s - FAIL expected stderr '%s', got '%s'", tc.nm, tc.stderr, got)
+		}
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("http://HOST/a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("http://host/b\nhttp://[::1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{a, b}, strings.NewReader("http://stdin/"), &stdout, &stderr); status != 1 {
+		t.Errorf("FAIL expected status 1, got %d", status)
+	}
+	if want := "http://host/a\nhttp://host/b\n"; stdout.String() != want {
+		t.Errorf("FAIL expected stdout '%s', got '%s'", want, stdout.String())
+	}
+	if want := b + ":2: "; !strings.HasPrefix(stderr.String(), want) {
+		t.Errorf("FAIL expected stderr starting with '%s', got '%s'", want, stderr.String())
+	}
+
+	stderr.Reset()
+	if status := run([]string{filepath.Join(dir, "missing.txt")}, nil, &stdout, &stderr); status != 2 {
+		t.Errorf("FAIL expected status 2, got %d", status)
+	}
+	if !strings.HasPrefix(stderr.String(), "purell: ") {
+		t.Errorf("FAIL expected an error, got '%s'", stderr.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("no space left on device")
+}
+
+func TestRunWriteError(t *testing.T) {
+	var stderr bytes.Buffer
+	if status := run(nil, strings.NewReader("http://host/\n"), failingWriter{}, &stderr); status != 2 {
+		t.Errorf("FAIL expected status 2, got %d", status)
+	}
+	if want := "purell: no space left on device\n"; stderr.String() != want {
+		t.Errorf("FAIL expected stderr '%s', got '%s'", want, stderr.String())
+	}
+}
@@ -0,0 +1,111 @@
+package purell
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// A DirectoryIndex sets the file names removed by FlagRemoveDirectoryIndex,
+// see WithDirectoryIndex.
+type DirectoryIndex struct {
+	// Host selects the hosts the directory index applies to, in the forms
+	// accepted by QueryRule. An empty Host matches all hosts.
+	Host string
+	// Names are the base names of the index files, e.g. "index" or
+	// "welcome". If empty, the default names "default" and "index" are
+	// used.
+	Names []string
+	// Extensions are the extensions of the index files, without the dot,
+	// e.g. "html" or "aspx". If empty, any extension of 1 to 4 letters,
+	// digits or underscores is accepted.
+	Extensions []string
+	// IgnoreCase makes the names and extensions match case-insensitively.
+	IgnoreCase bool
+}
+
+// WithDirectoryIndex sets the file names removed by
+// FlagRemoveDirectoryIndex. The first directory index whose host matches
+// the host of the URL is used, and the default names (index and default,
+// with any extension of 1 to 4 characters) are removed if none matches.
+func WithDirectoryIndex(indexes ...DirectoryIndex) Option {
+	return func(n *Normalizer) error {
+		compiled := make([]dirIndex, len(indexes))
+		for i, d := range indexes {
+			if d.Host != "" {
+				p, err := newHostPattern(d.Host)
+				if err != nil {
+					return err
+				}
+				compiled[i].host = &p
+			}
+			rx, err := d.regexp()
+			if err != nil {
+				return err
+			}
+			compiled[i].rx = rx
+		}
+		n.funcs[FlagRemoveDirectoryIndex] = func(u *url.URL) {
+			removeDirectoryIndexes(u, compiled)
+		}
+		return nil
+	}
+}
+
+// regexp returns the regular expression that matches the index file at the
+// end of a path, in the form of rxDirIndex.
+func (d DirectoryIndex) regexp() (*regexp.Regexp, error) {
+	for _, list := range [][]string{d.Names, d.Extensions} {
+		for _, s := range list {
+			if s == "" || strings.Contains(s, "/") {
+				return nil, fmt.Errorf("purell: invalid directory index name or extension %q", s)
+			}
+		}
+	}
+
+	names := d.Names
+	if len(names) == 0 {
+		names = []string{"default", "index"}
+	}
+	ext := `\w{1,4}`
+	if len(d.Extensions) > 0 {
+		ext = "(?:" + quoteAlternatives(d.Extensions) + ")"
+	}
+
+	expr := `(^|/)((?:` + quoteAlternatives(names) + `)\.` + ext + `)$`
+	if d.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+func quoteAlternatives(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = regexp.QuoteMeta(s)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// dirIndex is the compiled form of a DirectoryIndex. The host is nil if it
+// matches all hosts.
+type dirIndex struct {
+	host *hostPattern
+	rx   *regexp.Regexp
+}
+
+func removeDirectoryIndexes(u *url.URL, indexes []dirIndex) {
+	if len(u.Path) == 0 {
+		return
+	}
+
+	host := hostname(u)
+	for _, d := range indexes {
+		if d.host == nil || d.host.match(host) {
+			u.Path = d.rx.ReplaceAllString(u.Path, "$1")
+			return
+		}
+	}
+	removeDirectoryIndex(u)
+}
@@ -0,0 +1,74 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestWithDirectoryIndex(t *testing.T) {
+	n, err := NewNormalizer(FlagRemoveDirectoryIndex, WithDirectoryIndex(
+		DirectoryIndex{Host: "*.example.com", Names: []string{"default"}, Extensions: []string{"aspx"}, IgnoreCase: true},
+		DirectoryIndex{Host: "site:example.org", Names: []string{"welcome"}, Extensions: []string{"jsp"}},
+		DirectoryIndex{Names: []string{"index", "home"}, Extensions: []string{"html", "shtml", "php"}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		src string
+		res string
+	}{
+		{"http://www.example.com/a/Default.ASPX", "http://www.example.com/a/"},
+		{"http://www.example.com/a/default.aspx?x=1", "http://www.example.com/a/?x=1"},
+		{"http://www.example.com/a/index.html", "http://www.example.com/a/index.html"},
+		{"http://a.example.org/welcome.jsp", "http://a.example.org/"},
+		{"http://a.example.org/Welcome.jsp", "http://a.example.org/Welcome.jsp"},
+		{"http://other.com/index.shtml", "http://other.com/"},
+		{"http://other.com/a/home.html", "http://other.com/a/"},
+		{"http://other.com/a/home.htm", "http://other.com/a/home.htm"},
+		{"http://other.com/a/myindex.html", "http://other.com/a/myindex.html"},
+		{"http://other.com/a/index.html/b", "http://other.com/a/index.html/b"},
+	}
+	for _, tc := range testcases {
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.src, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.src, tc.res, got)
+		}
+	}
+}
+
+func TestWithDirectoryIndexDefault(t *testing.T) {
+	n := MustNewNormalizer(FlagRemoveDirectoryIndex, WithDirectoryIndex(
+		DirectoryIndex{Host: "example.com", Extensions: []string{"html"}},
+	))
+	testcases := []struct {
+		src string
+		res string
+	}{
+		{"http://example.com/default.html", "http://example.com/"},
+		{"http://example.com/index.php", "http://example.com/index.php"},
+		{"http://other.com/index.php", "http://other.com/"},
+		{"http://other.com/index.shtml", "http://other.com/index.shtml"},
+	}
+	for _, tc := range testcases {
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.src, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.src, tc.res, got)
+		}
+	}
+}
+
+func TestWithDirectoryIndexInvalid(t *testing.T) {
+	for _, d := range []DirectoryIndex{
+		{Host: "a*b.com"},
+		{Names: []string{""}},
+		{Names: []string{"a/index"}},
+		{Extensions: []string{""}},
+	} {
+		if _, err := NewNormalizer(FlagRemoveDirectoryIndex, WithDirectoryIndex(d)); err == nil {
+			t.Errorf("%+v - FAIL expected error", d)
+		}
+	}
+}
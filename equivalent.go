@@ -0,0 +1,58 @@
+package purell
+
+// Equivalent returns true if the two URL strings are the same once
+// normalized with the flags, or an error if either of them can't be parsed
+// into an URL object.
+func Equivalent(a, b string, f NormalizationFlags) (bool, error) {
+	c, err := FirstDifference(a, b, f)
+	return c == 0, err
+}
+
+// FirstDifference normalizes the two URL strings with the flags, and
+// returns the first part of the URL, in the order they appear in an URL
+// string, that differs between them. It returns zero if the URLs are
+// equivalent, or an error if either of them can't be parsed into an URL
+// object.
+func FirstDifference(a, b string, f NormalizationFlags) (Component, error) {
+	n, err := NewNormalizer(f)
+	if err != nil {
+		return 0, err
+	}
+	return n.FirstDifference(a, b)
+}
+
+// Equivalent returns true if the two URL strings are the same once
+// normalized. It is the equivalent of the Equivalent function.
+func (n *Normalizer) Equivalent(a, b string) (bool, error) {
+	c, err := n.FirstDifference(a, b)
+	return c == 0, err
+}
+
+// FirstDifference returns the first part of the URL that differs between
+// the two normalized URL strings. It is the equivalent of the
+// FirstDifference function.
+func (n *Normalizer) FirstDifference(a, b string) (Component, error) {
+	ua, xa, err := n.parse(a)
+	if err != nil {
+		return 0, err
+	}
+	ub, xb, err := n.parse(b)
+	if err != nil {
+		return 0, err
+	}
+	n.apply(ua)
+	n.apply(ub)
+	if n.serialize(ua, xa) == n.serialize(ub, xb) {
+		return 0, nil
+	}
+
+	c := diffComponents(ua, ub)
+	if xa.userinfo != xb.userinfo {
+		c |= ComponentUserinfo
+	}
+	if xa.forceFrag != xb.forceFrag {
+		c |= ComponentFragment
+	}
+	// Keep the lowest bit, components are declared in URL order
+	return c & -c, nil
+}
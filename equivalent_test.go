@@ -0,0 +1,67 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestFirstDifference(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		a    string
+		b    string
+		flgs NormalizationFlags
+		res  Component
+	}{
+		{"Same", "http://host/path", "http://host/path", 0, 0},
+		{"SafeEquivalent", "HTTP://Host:80/path", "http://host/path", FlagsSafe, 0},
+		{"UnsafeEquivalent", "http://www.host/a/../path/?b=2&a=1#frag", "http://host/path?a=1&b=2", FlagsUnsafeGreedy, 0},
+		{"IPv6Equivalent", "http://[2001:DB8:0:0:0:0:0:1]/", "http://[2001:db8::1]/", FlagCanonicalizeIPv6Host, 0},
+		{"Scheme", "https://host/path", "http://host/other", FlagsSafe, ComponentScheme},
+		{"Userinfo", "http://user@host/path", "http://host/path", FlagsSafe, ComponentUserinfo},
+		{"Host", "http://host/path", "http://www.host/path", FlagsSafe, ComponentHost},
+		{"HostAndPath", "http://host/a", "http://other/b", FlagsSafe, ComponentHost},
+		{"Port", "http://host:8080/path", "http://host/path", FlagsSafe, ComponentPort},
+		{"EmptyPort", "http://host:/path", "http://host/path", FlagsSafe, ComponentPort},
+		{"Path", "http://host/path/", "http://host/path", FlagsSafe, ComponentPath},
+		{"PathCase", "http://host/A", "http://host/a", FlagsSafe, ComponentPath},
+		{"Query", "http://host/path?b=2&a=1", "http://host/path?a=1&b=2", FlagsSafe, ComponentQuery},
+		{"QueryAndFragment", "http://host/path?a=1#x", "http://host/path?a=2#y", FlagsSafe, ComponentQuery},
+		{"Fragment", "http://host/path#x", "http://host/path", FlagsSafe, ComponentFragment},
+	}
+
+	for _, tc := range testcases {
+		if got, err := FirstDifference(tc.a, tc.b, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+		if got, err := Equivalent(tc.a, tc.b, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != (tc.res == 0) {
+			t.Errorf("%s - FAIL expected %t, got %t", tc.nm, tc.res == 0, got)
+		}
+	}
+}
+
+func TestEquivalentErrors(t *testing.T) {
+	if _, err := Equivalent("http://host/", "http://[::1", FlagsSafe); err == nil {
+		t.Errorf("FAIL expected error for an invalid second URL")
+	}
+	if _, err := Equivalent("http://256.0.0.1/", "http://host/", FlagDecodeIPv4Host); err == nil {
+		t.Errorf("FAIL expected error for an invalid first URL")
+	}
+}
+
+func TestNormalizerEquivalent(t *testing.T) {
+	n := MustNewNormalizer(FlagsSafe, WithWHATWG())
+	if ok, err := n.Equivalent("HTTP://host:80/a/../b", "http://host/b"); err != nil {
+		t.Errorf("FAIL : %s", err)
+	} else if !ok {
+		t.Errorf("FAIL expected equivalent URLs")
+	}
+	if c, err := n.FirstDifference("http://host/b#", "http://host/b"); err != nil {
+		t.Errorf("FAIL : %s", err)
+	} else if c != ComponentFragment {
+		t.Errorf("FAIL expected '%s', got '%s'", ComponentFragment, c)
+	}
+}
@@ -0,0 +1,70 @@
+package purell
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// rxDrivePath matches a Windows drive letter at the start of the path of a
+// file: URL, e.g. /C:/Docs or /c|/docs.
+var rxDrivePath = regexp.MustCompile(`^/?([A-Za-z])[:|](/|$)`)
+
+// normalizeFile normalizes file: URLs (RFC 8089): the localhost host is
+// dropped, drive letters are uppercased and written with a colon (as in
+// file:///C:/Docs), and dot segments are removed without going above the
+// root or the drive letter. UNC hosts (as in file://server/share) are kept.
+// Only string transformations are involved, the result does not depend on
+// the operating system.
+func normalizeFile(u *url.URL) {
+	if u.Opaque != "" {
+		// file:c:/docs
+		p, err := url.PathUnescape(u.Opaque)
+		if err != nil {
+			return
+		}
+		u.Path, u.Opaque = p, ""
+	}
+
+	if strings.EqualFold(u.Host, "localhost") {
+		u.Host = ""
+	} else if len(u.Host) == 2 && rxDrivePath.MatchString(u.Host) {
+		// file://C:/Docs
+		u.Path = "/" + u.Host + u.Path
+		u.Host = ""
+	}
+
+	var drive string
+	p := u.Path
+	if m := rxDrivePath.FindStringSubmatch(p); m != nil {
+		drive = "/" + strings.ToUpper(m[1]) + ":"
+		p = p[len(m[0])-len(m[2]):]
+	}
+	u.Path = drive + removeFileDotSegments(p)
+	u.RawPath = ""
+}
+
+// removeFileDotSegments removes the dot segments of an absolute path, or of
+// an empty one, which stands for the root.
+func removeFileDotSegments(p string) string {
+	segments := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	resolved := make([]string, 0, len(segments))
+	for i, s := range segments {
+		last := i == len(segments)-1
+		switch s {
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+		case ".":
+		default:
+			resolved = append(resolved, s)
+			continue
+		}
+		// A path that ends with a dot segment is a directory
+		if last {
+			resolved = append(resolved, "")
+		}
+	}
+	return "/" + strings.Join(resolved, "/")
+}
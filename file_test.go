@@ -0,0 +1,42 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestNormalizeFile(t *testing.T) {
+	testcases := []struct {
+		nm  string
+		src string
+		res string
+	}{
+		{"Drive", "file:///C:/Docs", "file:///C:/Docs"},
+		{"DriveLowercase", "file:///c:/docs", "file:///C:/docs"},
+		{"DrivePipe", "file:///c|/docs", "file:///C:/docs"},
+		{"DriveRoot", "file:///c:", "file:///C:/"},
+		{"DriveRootPipe", "file:///C|/", "file:///C:/"},
+		{"DriveAsHost", "file://c:/Docs", "file:///C:/Docs"},
+		{"DriveOpaque", "file:c|/Docs", "file:///C:/Docs"},
+		{"DriveDotSegments", "file:///C:/Docs/../../Windows/./System32", "file:///C:/Windows/System32"},
+		{"DriveDotSegmentsEnd", "file:///C:/Docs/..", "file:///C:/"},
+		{"DriveNotFirst", "file:///docs/c:/x", "file:///docs/c:/x"},
+		{"DriveLongerSegment", "file:///cd:/x", "file:///cd:/x"},
+		{"Localhost", "file://localhost/etc/hosts", "file:///etc/hosts"},
+		{"LocalhostUppercase", "file://LOCALHOST/etc/hosts", "file:///etc/hosts"},
+		{"LocalhostDrive", "file://localhost/c|/Docs", "file:///C:/Docs"},
+		{"UNC", "file://server/share/docs", "file://server/share/docs"},
+		{"UNCDotSegments", "file://server/share/a/../b/.", "file://server/share/b/"},
+		{"UNCNoPath", "file://server", "file://server/"},
+		{"RootDotSegments", "file:///../../etc/./passwd", "file:///etc/passwd"},
+		{"EmptyPath", "file://", "file:///"},
+		{"Escapes", "file:///C:/My%20Docs/a%2fb", "file:///C:/My%20Docs/a/b"},
+	}
+
+	for _, tc := range testcases {
+		if got, err := NormalizeURLString(tc.src, FlagSchemeSpecific); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
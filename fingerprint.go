@@ -0,0 +1,63 @@
+package purell
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"net/url"
+)
+
+// FingerprintVersion is the version of the algorithm used by the
+// fingerprint functions. It is bumped if the algorithm ever changes, so that
+// stored fingerprints can be recomputed. Note that the fingerprints also
+// change if the normalized form of an URL does.
+//
+// Version 1 computes the SHA-256 digest of the normalized URL string, and
+// keeps its first 16 bytes for 128-bit fingerprints, or its first 8 bytes
+// read as a big-endian integer for 64-bit fingerprints.
+const FingerprintVersion = 1
+
+// Fingerprint64 returns the 64-bit fingerprint of the URL object normalized
+// with the flags. Like NormalizeURL, it modifies the URL object.
+func Fingerprint64(u *url.URL, f NormalizationFlags) uint64 {
+	return fingerprint64(NormalizeURL(u, f))
+}
+
+// Fingerprint128 returns the 128-bit fingerprint of the URL object
+// normalized with the flags. Like NormalizeURL, it modifies the URL object.
+func Fingerprint128(u *url.URL, f NormalizationFlags) [16]byte {
+	return fingerprint128(NormalizeURL(u, f))
+}
+
+// FingerprintString64 returns the 64-bit fingerprint of the URL string
+// normalized with the flags, or an error if it can't be parsed into an URL
+// object.
+func FingerprintString64(u string, f NormalizationFlags) (uint64, error) {
+	s, err := NormalizeURLString(u, f)
+	if err != nil {
+		return 0, err
+	}
+	return fingerprint64(s), nil
+}
+
+// FingerprintString128 returns the 128-bit fingerprint of the URL string
+// normalized with the flags, or an error if it can't be parsed into an URL
+// object.
+func FingerprintString128(u string, f NormalizationFlags) ([16]byte, error) {
+	s, err := NormalizeURLString(u, f)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	return fingerprint128(s), nil
+}
+
+func fingerprint64(normalized string) uint64 {
+	sum := sha256.Sum256([]byte(normalized))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func fingerprint128(normalized string) [16]byte {
+	var fp [16]byte
+	sum := sha256.Sum256([]byte(normalized))
+	copy(fp[:], sum[:16])
+	return fp
+}
@@ -0,0 +1,73 @@
+package purell
+
+import (
+	"encoding/hex"
+	"net/url"
+	"testing"
+)
+
+// The fingerprints of version 1 must never change.
+func TestFingerprintVersion1(t *testing.T) {
+	testcases := []struct {
+		nm    string
+		src   string
+		flgs  NormalizationFlags
+		res64 uint64
+		res   string
+	}{
+		{
+			"Normalized",
+			"HTTP://Host:80/path/",
+			FlagsUsuallySafeGreedy,
+			0x747f8a4f4108bf7d,
+			"747f8a4f4108bf7db3ad4caa44376e9d",
+		},
+		{
+			"AsIs",
+			"http://www.example.com/",
+			0,
+			0x14b570acce514512,
+			"14b570acce51451285fa2340e01f9734",
+		},
+	}
+
+	if FingerprintVersion != 1 {
+		t.Fatalf("FAIL expected version 1, got %d", FingerprintVersion)
+	}
+	for _, tc := range testcases {
+		if got, err := FingerprintString64(tc.src, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res64 {
+			t.Errorf("%s - FAIL expected '%x', got '%x'", tc.nm, tc.res64, got)
+		}
+		if got, err := FingerprintString128(tc.src, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if hex.EncodeToString(got[:]) != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%x'", tc.nm, tc.res, got)
+		}
+
+		u, _ := url.Parse(tc.src)
+		if got := Fingerprint64(u, tc.flgs); got != tc.res64 {
+			t.Errorf("%s - FAIL expected '%x', got '%x'", tc.nm, tc.res64, got)
+		}
+		u, _ = url.Parse(tc.src)
+		if got := Fingerprint128(u, tc.flgs); hex.EncodeToString(got[:]) != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%x'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestFingerprintEquivalent(t *testing.T) {
+	a, _ := FingerprintString128("http://www.host/a/../path/?b=2&a=1", FlagsUnsafeGreedy)
+	b, _ := FingerprintString128("HTTP://host:80/path?a=1&b=2", FlagsUnsafeGreedy)
+	c, _ := FingerprintString128("http://host/other", FlagsUnsafeGreedy)
+	if a != b {
+		t.Errorf("FAIL expected equal fingerprints, got '%x' and '%x'", a, b)
+	}
+	if a == c {
+		t.Errorf("FAIL expected different fingerprints, got '%x'", a)
+	}
+	if _, err := FingerprintString64("http://[::1", FlagsSafe); err == nil {
+		t.Errorf("FAIL expected error")
+	}
+}
@@ -0,0 +1,128 @@
+package purell
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Names of the convenience sets, from the largest to the smallest.
+var setNames = []struct {
+	name  string
+	flags NormalizationFlags
+}{
+	{"all-greedy", FlagsAllGreedy},
+	{"all-non-greedy", FlagsAllNonGreedy},
+	{"unsafe-greedy", FlagsUnsafeGreedy},
+	{"unsafe-non-greedy", FlagsUnsafeNonGreedy},
+	{"usually-safe-greedy", FlagsUsuallySafeGreedy},
+	{"usually-safe-non-greedy", FlagsUsuallySafeNonGreedy},
+	{"safe", FlagsSafe},
+}
+
+// flagsByKey maps the keys of the names of the flags and convenience sets
+// to their value, see flagKey.
+var flagsByKey = func() map[string]NormalizationFlags {
+	m := make(map[string]NormalizationFlags, len(flagNames)+len(setNames))
+	for f, name := range flagNames {
+		m[flagKey(name)] = f
+	}
+	for _, s := range setNames {
+		m[flagKey(s.name)] = s.flags
+	}
+	return m
+}()
+
+// flagKey returns the lookup key of a flag name, so that names can be given
+// either as in "usually-safe-greedy" or as the name of the constant, as in
+// "FlagsUsuallySafeGreedy".
+func flagKey(name string) string {
+	if strings.HasPrefix(name, "Flags") {
+		name = name[len("Flags"):]
+	} else if strings.HasPrefix(name, "Flag") {
+		name = name[len("Flag"):]
+	}
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+}
+
+// String returns the names of the flags separated by "|", e.g.
+// "usually-safe-greedy|sort-query". The largest convenience set included in
+// the flags is used, if any. Bits that are not flags are written as a
+// number. ParseFlags parses the result back to the same flags.
+func (f NormalizationFlags) String() string {
+	if f == 0 {
+		return "none"
+	}
+
+	var names []string
+	for _, s := range setNames {
+		if f&s.flags == s.flags {
+			names = append(names, s.name)
+			f &^= s.flags
+			break
+		}
+	}
+	for rest := f; rest != 0; {
+		flag := NormalizationFlags(1) << bits.TrailingZeros(uint(rest))
+		rest &^= flag
+		if name, ok := flagNames[flag]; ok {
+			names = append(names, name)
+			f &^= flag
+		}
+	}
+	if f != 0 {
+		names = append(names, strconv.FormatUint(uint64(f), 10))
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseFlags parses the flags from a list of names separated by "|" or ",",
+// as returned by NormalizationFlags.String. The names of the flags and of
+// the convenience sets are those of their constant, either as is (e.g.
+// "FlagsUsuallySafeGreedy") or in lowercase words separated by dashes and
+// without prefix (e.g. "usually-safe-greedy"). A name prefixed with "-"
+// removes the flags from those before it, e.g.
+// "safe|sort-query|-remove-default-port". Numbers are accepted too, and
+// "none" stands for no flags.
+func ParseFlags(s string) (NormalizationFlags, error) {
+	var f NormalizationFlags
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == '|' || r == ',' }) {
+		name = strings.TrimSpace(name)
+		remove := strings.HasPrefix(name, "-")
+		if remove {
+			name = strings.TrimSpace(name[1:])
+		}
+
+		var flags NormalizationFlags
+		if n, err := strconv.ParseUint(name, 0, 0); err == nil {
+			flags = NormalizationFlags(n)
+		} else if name == "none" || name == "" {
+			flags = 0
+		} else if flags = flagsByKey[flagKey(name)]; flags == 0 {
+			return 0, fmt.Errorf("purell: unknown normalization flag %q", name)
+		}
+
+		if remove {
+			f &^= flags
+		} else {
+			f |= flags
+		}
+	}
+	return f, nil
+}
+
+// MarshalText implements encoding.TextMarshaler, using String.
+func (f NormalizationFlags) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, using ParseFlags.
+func (f *NormalizationFlags) UnmarshalText(text []byte) error {
+	flags, err := ParseFlags(string(text))
+	if err != nil {
+		return err
+	}
+	*f = flags
+	return nil
+}
@@ -0,0 +1,110 @@
+package purell
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestFlagsString(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		flgs NormalizationFlags
+		res  string
+	}{
+		{"None", 0, "none"},
+		{"Single", FlagSortQuery, "sort-query"},
+		{"Several", FlagRemoveFragment | FlagSortQuery, "remove-fragment|sort-query"},
+		{"Set", FlagsUsuallySafeGreedy, "usually-safe-greedy"},
+		{"LargestSet", FlagsAllNonGreedy, "all-non-greedy"},
+		{"SetAndFlags", FlagsSafe | FlagSortQuery | FlagRemoveTrackingParams, "safe|sort-query|remove-tracking-params"},
+		{"PartialSet", FlagsSafe &^ FlagLowercaseHost, "lowercase-scheme|uppercase-escapes|decode-unnecessary-escapes|encode-necessary-escapes|remove-default-port|remove-empty-query-separator"},
+		{"UnknownBits", FlagLowercaseHost | 1<<31, "lowercase-host|2147483648"},
+	}
+
+	for _, tc := range testcases {
+		if got := tc.flgs.String(); got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+	}{
+		{"Empty", "", 0},
+		{"None", "none", 0},
+		{"Single", "sort-query", FlagSortQuery},
+		{"Pipes", "safe|sort-query|remove-fragment", FlagsSafe | FlagSortQuery | FlagRemoveFragment},
+		{"Commas", " safe, sort-query ,remove-fragment ", FlagsSafe | FlagSortQuery | FlagRemoveFragment},
+		{"Subtraction", "usually-safe-greedy|-remove-trailing-slash", FlagsUsuallySafeGreedy &^ FlagRemoveTrailingSlash},
+		{"SubtractionOrder", "-sort-query|sort-query", FlagSortQuery},
+		{"ConstantNames", "FlagsUsuallySafeGreedy|FlagSortQuery|FlagDecodeDWORDHost", FlagsUsuallySafeGreedy | FlagSortQuery | FlagDecodeDWORDHost},
+		{"CaseInsensitive", "Remove-WWW|ADD_TRAILING_SLASH", FlagRemoveWWW | FlagAddTrailingSlash},
+		{"Numbers", "3|0x4", FlagLowercaseScheme | FlagLowercaseHost | FlagUppercaseEscapes},
+		{"IPv6", "canonicalize-ipv6-host", FlagCanonicalizeIPv6Host},
+	}
+
+	for _, tc := range testcases {
+		if got, err := ParseFlags(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.flgs {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.flgs, got)
+		}
+	}
+
+	for _, src := range []string{"unknown", "safe|nope", "-unknown", "Flag", "sort query"} {
+		if got, err := ParseFlags(src); err == nil {
+			t.Errorf("%s - FAIL expected error, got '%s'", src, got)
+		}
+	}
+}
+
+func TestFlagsRoundTrip(t *testing.T) {
+	for f := range flagNames {
+		if got, err := ParseFlags(f.String()); err != nil || got != f {
+			t.Errorf("%s - FAIL expected round trip, got '%s' (%v)", f, got, err)
+		}
+	}
+	for _, s := range setNames {
+		if got, err := ParseFlags(s.flags.String()); err != nil || got != s.flags {
+			t.Errorf("%s - FAIL expected round trip, got '%s' (%v)", s.name, got, err)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		f := NormalizationFlags(r.Uint32())
+		if got, err := ParseFlags(f.String()); err != nil || got != f {
+			t.Errorf("%d - FAIL expected round trip of '%s', got '%s' (%v)", uint(f), f, got, err)
+		}
+	}
+}
+
+func TestFlagsText(t *testing.T) {
+	type config struct {
+		Flags NormalizationFlags `json:"flags"`
+	}
+
+	b, err := json.Marshal(config{FlagsSafe | FlagSortQuery})
+	if err != nil {
+		t.Fatalf("FAIL : %s", err)
+	}
+	if want := `{"flags":"safe|sort-query"}`; string(b) != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, b)
+	}
+
+	var c config
+	if err := json.Unmarshal([]byte(`{"flags":"usually-safe-greedy|-remove-trailing-slash"}`), &c); err != nil {
+		t.Fatalf("FAIL : %s", err)
+	}
+	if want := FlagsUsuallySafeGreedy &^ FlagRemoveTrailingSlash; c.Flags != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, c.Flags)
+	}
+	if err := json.Unmarshal([]byte(`{"flags":"unknown"}`), &c); err == nil {
+		t.Errorf("FAIL expected error")
+	}
+}
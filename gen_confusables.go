@@ -0,0 +1,87 @@
+//go:build ignore
+
+// This program generates confusables.go from the confusables data of
+// UTS #39 in data/confusables.txt. Run it with go generate.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	f, err := os.Open("data/confusables.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by gen_confusables.go from data/confusables.txt. DO NOT EDIT.
+
+package purell
+
+// confusables maps characters to their prototype for the skeletons of
+// UTS #39.
+var confusables = map[rune]string{
+`)
+
+	s := bufio.NewScanner(f)
+	n := 0
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimPrefix(s.Text(), "\uFEFF")
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fields := strings.Split(text, ";")
+		if len(fields) < 2 {
+			log.Fatalf("data/confusables.txt:%d: invalid line", line)
+		}
+		src, err := parseRune(fields[0])
+		if err != nil {
+			log.Fatalf("data/confusables.txt:%d: %v", line, err)
+		}
+		var target strings.Builder
+		for _, cp := range strings.Fields(fields[1]) {
+			r, err := parseRune(cp)
+			if err != nil {
+				log.Fatalf("data/confusables.txt:%d: %v", line, err)
+			}
+			target.WriteRune(r)
+		}
+		fmt.Fprintf(&buf, "\t0x%04X: %+q,\n", src, target.String())
+		n++
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+	buf.WriteString("}\n")
+	if n == 0 {
+		log.Fatal("data/confusables.txt: no mappings")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("confusables.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseRune(s string) (rune, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid code point %q", s)
+	}
+	return rune(v), nil
+}
@@ -0,0 +1,153 @@
+package purell
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// A HandlerOption configures the handler returned by CanonicalHandler.
+type HandlerOption func(*canonicalHandler)
+
+// WithRedirectCode sets the status code of the redirects, which defaults to
+// http.StatusMovedPermanently. Use http.StatusPermanentRedirect to keep the
+// method and body of non-GET requests.
+func WithRedirectCode(code int) HandlerOption {
+	return func(h *canonicalHandler) {
+		h.code = code
+	}
+}
+
+// WithRewrite makes the handler rewrite the URL of the request to its
+// canonical form and call the wrapped handler, instead of redirecting. The
+// scheme of the request cannot be rewritten.
+func WithRewrite() HandlerOption {
+	return func(h *canonicalHandler) {
+		h.rewrite = true
+	}
+}
+
+// WithForwardedProto makes the handler take the scheme of the request from
+// the X-Forwarded-Proto or Forwarded headers, if present. It must only be
+// used behind a proxy that sets these headers.
+func WithForwardedProto() HandlerOption {
+	return func(h *canonicalHandler) {
+		h.forwarded = true
+	}
+}
+
+// CanonicalHandler returns a handler that normalizes the absolute URL of
+// each request, rebuilt from its scheme, Host and RequestURI, and redirects
+// the request to the normalized URL if it differs. Requests whose URL is
+// already canonical are passed to the wrapped handler.
+//
+// To protect against redirect loops, the request is passed to the wrapped
+// handler as is if normalizing the normalized URL changes it again.
+func CanonicalHandler(next http.Handler, n *Normalizer, opts ...HandlerOption) http.Handler {
+	h := &canonicalHandler{next: next, n: n, code: http.StatusMovedPermanently}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type canonicalHandler struct {
+	next      http.Handler
+	n         *Normalizer
+	code      int
+	rewrite   bool
+	forwarded bool
+}
+
+func (h *canonicalHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	raw, ok := h.requestURL(r)
+	if !ok {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	canonical, err := h.n.NormalizeString(raw)
+	if err != nil || canonical == raw {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	// Loop protection, the canonical URL must be stable
+	if again, err := h.n.NormalizeString(canonical); err != nil || again != canonical {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	if !h.rewrite {
+		http.Redirect(w, r, canonical, h.code)
+		return
+	}
+
+	u, err := url.Parse(canonical)
+	if err != nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path, r2.URL.RawPath, r2.URL.RawQuery = u.Path, u.RawPath, u.RawQuery
+	if r.URL.Host != "" {
+		r2.URL.Host = u.Host
+	}
+	r2.Host = u.Host
+	r2.RequestURI = u.RequestURI()
+	h.next.ServeHTTP(w, r2)
+}
+
+// requestURL returns the absolute URL of the request. It returns false if
+// the request is not for an URL, e.g. "OPTIONS *".
+func (h *canonicalHandler) requestURL(r *http.Request) (string, bool) {
+	requestURI := r.RequestURI
+	if requestURI == "" {
+		requestURI = r.URL.RequestURI()
+	}
+	if requestURI == "*" {
+		return "", false
+	}
+	if !strings.HasPrefix(requestURI, "/") {
+		// Absolute-form, as sent to proxies
+		return requestURI, true
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if h.forwarded {
+		if proto := forwardedProto(r.Header); proto != "" {
+			scheme = proto
+		}
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	return scheme + "://" + host + requestURI, true
+}
+
+// forwardedProto returns the scheme of the first proxy in the Forwarded or
+// X-Forwarded-Proto headers, or an empty string.
+func forwardedProto(header http.Header) string {
+	var proto string
+	if fwd := header.Get("Forwarded"); fwd != "" {
+		first, _, _ := strings.Cut(fwd, ",")
+		for _, pair := range strings.Split(first, ";") {
+			if k, v, _ := strings.Cut(strings.TrimSpace(pair), "="); strings.EqualFold(k, "proto") {
+				proto = strings.Trim(v, `"`)
+			}
+		}
+	} else {
+		proto, _, _ = strings.Cut(header.Get("X-Forwarded-Proto"), ",")
+	}
+
+	proto = strings.ToLower(strings.TrimSpace(proto))
+	if proto == "http" || proto == "https" {
+		return proto
+	}
+	return ""
+}
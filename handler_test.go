@@ -0,0 +1,109 @@
+package purell
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalHandler(t *testing.T) {
+	testcases := []struct {
+		nm       string
+		flgs     NormalizationFlags
+		opts     []HandlerOption
+		method   string
+		target   string
+		header   map[string]string
+		code     int
+		location string
+	}{
+		{"Canonical", FlagsUsuallySafeGreedy, nil, "GET", "http://example.com/a/b?x=1", nil, http.StatusOK, ""},
+		{"Redirect", FlagsUsuallySafeGreedy, nil, "GET", "http://Example.com:80/a/./b/?x=1", nil, http.StatusMovedPermanently, "http://example.com/a/b?x=1"},
+		{"RedirectCode", FlagsUsuallySafeGreedy, []HandlerOption{WithRedirectCode(http.StatusPermanentRedirect)}, "POST", "http://example.com/a/", nil, http.StatusPermanentRedirect, "http://example.com/a"},
+		{"TLS", FlagsSafe, nil, "GET", "https://example.com:443/", nil, http.StatusMovedPermanently, "https://example.com/"},
+		{"ForwardedIgnored", FlagsSafe, nil, "GET", "http://example.com:443/", map[string]string{"X-Forwarded-Proto": "https"}, http.StatusOK, ""},
+		{"XForwardedProto", FlagsSafe, []HandlerOption{WithForwardedProto()}, "GET", "http://example.com:443/", map[string]string{"X-Forwarded-Proto": "HTTPS, http"}, http.StatusMovedPermanently, "https://example.com/"},
+		{"Forwarded", FlagsSafe, []HandlerOption{WithForwardedProto()}, "GET", "http://example.com:443/", map[string]string{"Forwarded": `for=192.0.2.60;proto="https";by=203.0.113.43`}, http.StatusMovedPermanently, "https://example.com/"},
+		{"ForwardedInvalid", FlagsSafe, []HandlerOption{WithForwardedProto()}, "GET", "http://example.com:80/", map[string]string{"X-Forwarded-Proto": "javascript"}, http.StatusMovedPermanently, "http://example.com/"},
+		{"SortQuery", FlagSortQuery, nil, "GET", "http://example.com/?b=2&a=1", nil, http.StatusMovedPermanently, "http://example.com/?a=1&b=2"},
+		{"Asterisk", FlagsAllGreedy, nil, "OPTIONS", "*", nil, http.StatusOK, ""},
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tc := range testcases {
+		h := CanonicalHandler(ok, MustNewNormalizer(tc.flgs), tc.opts...)
+		r := httptest.NewRequest(tc.method, tc.target, nil)
+		if tc.target != "*" {
+			// Origin-form, as received by servers
+			r.RequestURI = r.URL.RequestURI()
+		}
+		for k, v := range tc.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.code {
+			t.Errorf("%s - FAIL expected status %d, got %d", tc.nm, tc.code, w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tc.location {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.location, got)
+		}
+	}
+}
+
+func TestCanonicalHandlerAbsoluteForm(t *testing.T) {
+	h := CanonicalHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		MustNewNormalizer(FlagsSafe), WithForwardedProto())
+
+	// The scheme of an absolute-form request URI wins over the headers
+	r := httptest.NewRequest("GET", "http://example.com:80/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got, want := w.Header().Get("Location"), "http://example.com/"; got != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestCanonicalHandlerRewrite(t *testing.T) {
+	var got *http.Request
+	h := CanonicalHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}), MustNewNormalizer(FlagsUsuallySafeGreedy|FlagSortQuery), WithRewrite())
+
+	r := httptest.NewRequest("GET", "http://Example.com:80/a/./b/?b=2&a=1", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("FAIL expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got == r {
+		t.Errorf("FAIL expected a copy of the request")
+	}
+	if got.URL.Path != "/a/b" || got.URL.RawQuery != "a=1&b=2" {
+		t.Errorf("FAIL expected '/a/b?a=1&b=2', got '%s?%s'", got.URL.Path, got.URL.RawQuery)
+	}
+	if got.Host != "example.com" || got.RequestURI != "/a/b?a=1&b=2" {
+		t.Errorf("FAIL expected host 'example.com' and request URI '/a/b?a=1&b=2', got '%s' and '%s'", got.Host, got.RequestURI)
+	}
+	if r.URL.Path != "/a/./b/" {
+		t.Errorf("FAIL expected the original request to be untouched, got '%s'", r.URL.Path)
+	}
+}
+
+func TestCanonicalHandlerLoop(t *testing.T) {
+	// A step that never produces a stable URL must not cause redirects
+	n := MustNewNormalizer(FlagsSafe, WithStep(Step{
+		Name: "unstable",
+		Func: func(u *url.URL) { u.Path += "x" },
+	}))
+	h := CanonicalHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), n)
+
+	r := httptest.NewRequest("GET", "http://example.com/a", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("FAIL expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,200 @@
+package purell
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// A HomographError is returned for a host with a label that could be
+// mistaken for another one: either it has compatibility characters that
+// NFKC maps to other ones (e.g. "𝐚pple", where the first letter is a
+// mathematical bold "a"), or its letters are of mixed scripts (e.g. "аpple",
+// where the first letter is Cyrillic), or they are of a single script other
+// than Latin but its skeleton is all ASCII (e.g. "аре").
+type HomographError struct {
+	Host          string // the host, in Unicode
+	Label         string
+	Skeleton      string // the skeleton of the label
+	Compatibility bool
+	MixedScript   bool
+}
+
+func (e *HomographError) Error() string {
+	if e.Compatibility {
+		return fmt.Sprintf("purell: label %q in host %q has compatibility characters", e.Label, e.Host)
+	}
+	if e.MixedScript {
+		return fmt.Sprintf("purell: label %q in host %q mixes scripts", e.Label, e.Host)
+	}
+	return fmt.Sprintf("purell: label %q in host %q is confusable with %q", e.Label, e.Host, e.Skeleton)
+}
+
+// Sets of scripts allowed together in a label, as per the highly
+// restrictive level of UTS #39.
+var allowedScripts = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// CheckHomograph returns a *HomographError if a label of the host could be
+// mistaken for another one, see HomographError. The scripts of a label are
+// those of its NFKC form. The host may be in Unicode or in Punycode.
+func CheckHomograph(host string) error {
+	host = hostToUnicodeLabels(host)
+	for _, label := range strings.Split(host, ".") {
+		if isASCII(label) {
+			continue
+		}
+		nfkc := norm.NFKC.String(label)
+		scripts := labelScripts(nfkc)
+		if !scriptsAllowed(scripts) {
+			return &HomographError{Host: host, Label: label, Skeleton: skeleton(label), MixedScript: true}
+		}
+		if len(scripts) == 1 && scripts[0] != "Latin" {
+			if sk := skeleton(label); isASCII(sk) {
+				return &HomographError{Host: host, Label: label, Skeleton: sk}
+			}
+		}
+		if nfkc != label {
+			return &HomographError{Host: host, Label: label, Skeleton: skeleton(label), Compatibility: true}
+		}
+	}
+	return nil
+}
+
+//go:generate go run gen_confusables.go
+
+// Skeleton returns the skeleton of the host as defined by UTS #39, so that
+// confusable hosts (e.g. "apple.com" and "аpple.com", where the first
+// letter is Cyrillic) have the same skeleton. The host may be in Unicode or
+// in Punycode. As hosts are case-insensitive, the host and its skeleton are
+// lowercased, so that e.g. "g00gle.com" and "google.com" have the same
+// skeleton even though UTS #39 maps "0" to "O".
+func Skeleton(host string) string {
+	return strings.ToLower(skeleton(strings.ToLower(hostToUnicodeLabels(host))))
+}
+
+// WithHomographCheck makes the Normalizer check the hosts for homographs,
+// see CheckHomograph. If warn is nil, the *HomographError is returned by the
+// methods that take URL strings, such as NormalizeString, otherwise warn is
+// called with the URL string and the error, and the normalization continues.
+func WithHomographCheck(warn func(u string, err error)) Option {
+	return func(n *Normalizer) error {
+		n.homograph = true
+		n.homographWarn = warn
+		return nil
+	}
+}
+
+// checkHomograph checks the host of the URL string u for homographs, as
+// configured by WithHomographCheck.
+func (n *Normalizer) checkHomograph(u, host string) error {
+	if !n.homograph {
+		return nil
+	}
+	err := CheckHomograph(host)
+	if err != nil && n.homographWarn != nil {
+		n.homographWarn(u, err)
+		return nil
+	}
+	return err
+}
+
+// hostToUnicodeLabels converts the Punycode labels of the host to Unicode.
+// Labels that can't be converted are kept as is.
+func hostToUnicodeLabels(host string) string {
+	if !strings.Contains(host, "xn--") {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if u, err := idna.ToUnicode(label); err == nil {
+			labels[i] = u
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// skeleton maps the characters of the NFD form of s to their prototype in
+// the confusables data of UTS #39, and returns the NFD form of the result.
+func skeleton(s string) string {
+	s = norm.NFD.String(s)
+	var buf strings.Builder
+	for _, r := range s {
+		if p, ok := confusables[r]; ok {
+			buf.WriteString(p)
+		} else {
+			buf.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(buf.String())
+}
+
+// labelScripts returns the scripts of the letters of the label, in order of
+// appearance. Characters common to several scripts, such as digits and the
+// hyphen, are ignored.
+func labelScripts(label string) []string {
+	var scripts []string
+	for _, r := range label {
+		if s := script(r); s != "" && !containsString(scripts, s) {
+			scripts = append(scripts, s)
+		}
+	}
+	return scripts
+}
+
+// script returns the name of the script of the character, or an empty
+// string for the Common and Inherited scripts.
+func script(r rune) string {
+	if r < utf8.RuneSelf {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return "Latin"
+		}
+		return ""
+	}
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return ""
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// scriptsAllowed returns true if the scripts are allowed together in a
+// label.
+func scriptsAllowed(scripts []string) bool {
+	if len(scripts) <= 1 {
+		return true
+	}
+	for _, set := range allowedScripts {
+		ok := true
+		for _, s := range scripts {
+			if !containsString(set, s) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
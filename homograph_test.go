@@ -0,0 +1,110 @@
+package purell
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckHomograph(t *testing.T) {
+	testcases := []struct {
+		nm     string
+		host   string
+		label  string
+		mixed  bool
+		compat bool
+	}{
+		{"ASCII", "www.apple.com", "", false, false},
+		{"Latin", "www.café.com", "", false, false},
+		{"Cyrillic", "президент.рф", "", false, false},
+		{"Japanese", "日本語とカタカナ.jp", "", false, false},
+		{"JapaneseLatin", "abcひらがな.jp", "", false, false},
+		{"Greek", "ελληνικά.gr", "", false, false},
+		{"Digits", "123.example", "", false, false},
+		{"MixedCyrillic", "www.аpple.com", "аpple", true, false},
+		{"MixedPunycode", "www.xn--pple-43d.com", "аpple", true, false},
+		{"MixedGreek", "gοogle.com", "gοogle", true, false},
+		{"MixedHangulHiragana", "한국ひら.kr", "한국ひら", true, false},
+		{"WholeScript", "аре.com", "аре", false, false},
+		{"WholeScriptDigits", "ѕсоре1.com", "ѕсоре1", false, false},
+		{"MathematicalBold", "𝐚pple.com", "𝐚pple", false, true},
+		{"MathematicalBoldPunycode", "xn--pple-4u20c.com", "𝐚pple", false, true},
+		{"Fullwidth", "ａpple.com", "ａpple", false, true},
+		{"MathematicalBoldCyrillic", "𝐚рple.com", "𝐚рple", true, false},
+	}
+
+	for _, tc := range testcases {
+		err := CheckHomograph(tc.host)
+		if tc.label == "" {
+			if err != nil {
+				t.Errorf("%s - FAIL : %s", tc.nm, err)
+			}
+			continue
+		}
+		var herr *HomographError
+		if !errors.As(err, &herr) {
+			t.Errorf("%s - FAIL expected *HomographError, got %v", tc.nm, err)
+		} else if herr.Label != tc.label || herr.MixedScript != tc.mixed || herr.Compatibility != tc.compat {
+			t.Errorf("%s - FAIL expected '%s' (mixed: %t, compat: %t), got '%s' (mixed: %t, compat: %t)", tc.nm, tc.label, tc.mixed, tc.compat, herr.Label, herr.MixedScript, herr.Compatibility)
+		}
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	testcases := []struct {
+		a, b string
+		same bool
+	}{
+		{"apple.com", "аpple.com", true},
+		{"apple.com", "xn--pple-43d.com", true},
+		{"apple.com", "APPLE.com", true},
+		{"paypal.com", "paypa1.com", true},
+		{"google.com", "gοοgle.com", true},
+		{"scope.com", "ѕсоре.com", true},
+		{"apple.com", "𝐚pple.com", true},
+		{"apple.com", "xn--pple-4u20c.com", true},
+		{"google.com", "g00gle.com", true},
+		{"microsoft.com", "rnicrosoft.com", true},
+		{"apple.com", "apples.com", false},
+		{"café.com", "cafe.com", false},
+	}
+
+	for _, tc := range testcases {
+		if same := Skeleton(tc.a) == Skeleton(tc.b); same != tc.same {
+			t.Errorf("%s, %s - FAIL expected %t, got %t ('%s', '%s')", tc.a, tc.b, tc.same, same, Skeleton(tc.a), Skeleton(tc.b))
+		}
+	}
+}
+
+func TestWithHomographCheck(t *testing.T) {
+	const src = "http://www.аpple.com/"
+
+	n := MustNewNormalizer(FlagsSafe, WithHomographCheck(nil))
+	var herr *HomographError
+	if _, err := n.NormalizeString(src); !errors.As(err, &herr) {
+		t.Errorf("expected *HomographError, got %v", err)
+	}
+	if _, err := n.NormalizeString("http://www.apple.com/"); err != nil {
+		t.Error(err)
+	}
+	if _, err := n.NormalizeString("http://𝐚pple.com/"); !errors.As(err, &herr) {
+		t.Errorf("MathematicalBold - expected *HomographError, got %v", err)
+	}
+
+	var warned []string
+	n = MustNewNormalizer(FlagsSafe, WithHomographCheck(func(u string, err error) {
+		warned = append(warned, u)
+	}))
+	if got, err := n.NormalizeString(src); err != nil {
+		t.Error(err)
+	} else if got != "http://www.xn--pple-43d.com/" {
+		t.Errorf("expected 'http://www.xn--pple-43d.com/', got '%s'", got)
+	}
+	if len(warned) != 1 || warned[0] != src {
+		t.Errorf("expected a warning for '%s', got %v", src, warned)
+	}
+
+	n = MustNewNormalizer(FlagsSafe, WithWHATWG(), WithHomographCheck(nil))
+	if _, err := n.NormalizeString(src); !errors.As(err, &herr) {
+		t.Errorf("WHATWG - expected *HomographError, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package purell
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// hostPattern selects a set of hosts. It is compiled from one of the
+// following forms:
+//
+//	www.example.com   matches that host only
+//	*.example.com     matches any subdomain of example.com, but not example.com itself
+//	site:example.com  matches every host whose registrable domain is example.com
+type hostPattern struct {
+	host   string
+	suffix string
+	site   string
+}
+
+func newHostPattern(p string) (hostPattern, error) {
+	p = strings.TrimSuffix(strings.ToLower(p), ".")
+	switch {
+	case strings.HasPrefix(p, "site:"):
+		site := p[len("site:"):]
+		if etld1, err := publicsuffix.EffectiveTLDPlusOne(site); err != nil || etld1 != site {
+			return hostPattern{}, fmt.Errorf("purell: invalid host pattern %q: not a registrable domain", p)
+		}
+		return hostPattern{site: site}, nil
+	case strings.HasPrefix(p, "*."):
+		if len(p) == 2 {
+			return hostPattern{}, fmt.Errorf("purell: invalid host pattern %q", p)
+		}
+		return hostPattern{suffix: p[1:]}, nil
+	case p == "" || strings.ContainsAny(p, "*/:"):
+		return hostPattern{}, fmt.Errorf("purell: invalid host pattern %q", p)
+	}
+	return hostPattern{host: p}, nil
+}
+
+// match returns true if the host, as returned by hostname, matches the
+// pattern.
+func (p hostPattern) match(host string) bool {
+	switch {
+	case p.site != "":
+		etld1, err := publicsuffix.EffectiveTLDPlusOne(host)
+		return err == nil && etld1 == p.site
+	case p.suffix != "":
+		return strings.HasSuffix(host, p.suffix)
+	}
+	return host == p.host
+}
+
+// hostname returns the host of the URL in the form expected by
+// hostPattern, without port, brackets nor trailing dot, and lowercased.
+func hostname(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
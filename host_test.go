@@ -0,0 +1,47 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestHostPattern(t *testing.T) {
+	testcases := []struct {
+		pattern string
+		host    string
+		match   bool
+	}{
+		{"www.example.com", "www.example.com", true},
+		{"WWW.Example.com.", "www.example.com", true},
+		{"www.example.com", "example.com", false},
+		{"www.example.com", "a.www.example.com", false},
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{"site:example.com", "example.com", true},
+		{"site:example.com", "a.b.example.com", true},
+		{"site:example.com", "example.org", false},
+		{"site:example.co.uk", "www.example.co.uk", true},
+		{"site:example.co.uk", "co.uk", false},
+		{"site:example.com", "127.0.0.1", false},
+	}
+
+	for _, tc := range testcases {
+		p, err := newHostPattern(tc.pattern)
+		if err != nil {
+			t.Errorf("%s - FAIL : %s", tc.pattern, err)
+			continue
+		}
+		if got := p.match(tc.host); got != tc.match {
+			t.Errorf("%s - FAIL matching '%s': expected %v, got %v", tc.pattern, tc.host, tc.match, got)
+		}
+	}
+}
+
+func TestHostPatternInvalid(t *testing.T) {
+	for _, p := range []string{"", "*.", "a*.example.com", "example.com:80", "site:co.uk", "site:www.example.com"} {
+		if _, err := newHostPattern(p); err == nil {
+			t.Errorf("%s - FAIL expected error", p)
+		}
+	}
+}
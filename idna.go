@@ -0,0 +1,122 @@
+package purell
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// IDNAProfile is the processing applied to convert internationalized hosts
+// to their ASCII form.
+type IDNAProfile int
+
+const (
+	// IDNAPunycode only encodes the labels to Punycode, without validation.
+	// This is the default, as done by idna.ToASCII.
+	IDNAPunycode IDNAProfile = iota
+	// IDNALookup applies the nontransitional processing of UTS #46, for
+	// domain name lookup.
+	IDNALookup
+	// IDNARegistration validates the labels for registration, as per
+	// RFC 5891. The Bidi rule is always checked.
+	IDNARegistration
+	// IDNATransitional applies the transitional processing of UTS #46,
+	// where e.g. "ß" is mapped to "ss".
+	IDNATransitional
+)
+
+// IDNAOptions configures the conversion of internationalized hosts to
+// their ASCII form.
+type IDNAOptions struct {
+	Profile IDNAProfile
+	// AllowNonSTD3 disables the STD3 rules of the lookup, registration and
+	// transitional profiles, which limit the ASCII characters of the labels
+	// to letters, digits and the hyphen, e.g. to accept "a_b.example.com".
+	AllowNonSTD3 bool
+	// CheckBidi checks the Bidi rule of RFC 5893. It is always checked by
+	// the registration profile.
+	CheckBidi bool
+}
+
+// An IDNAError is returned when a host can't be converted to its ASCII
+// form. Label is the first invalid label of the host.
+type IDNAError struct {
+	Host  string
+	Label string
+	Err   error
+}
+
+func (e *IDNAError) Error() string {
+	return fmt.Sprintf("purell: invalid label %q in host %q: %s", e.Label, e.Host, e.Err)
+}
+
+// Unwrap returns the error of the idna package.
+func (e *IDNAError) Unwrap() error {
+	return e.Err
+}
+
+// WithIDNA sets the processing applied to convert internationalized hosts
+// to their ASCII form. Note that the lookup and transitional profiles also
+// map the host, e.g. to lowercase, while the registration profile rejects
+// the characters that would need mapping, such as uppercase letters, unless
+// FlagLowercaseHost is set. It has no effect in the WHATWG mode, which
+// always uses the processing of the URL Standard.
+func WithIDNA(opts IDNAOptions) Option {
+	return func(n *Normalizer) error {
+		var po []idna.Option
+		switch opts.Profile {
+		case IDNAPunycode:
+		case IDNALookup:
+			po = append(po, idna.MapForLookup())
+		case IDNARegistration:
+			po = append(po, idna.ValidateForRegistration())
+		case IDNATransitional:
+			po = append(po, idna.MapForLookup(), idna.Transitional(true))
+		default:
+			return fmt.Errorf("purell: unknown IDNA profile %d", opts.Profile)
+		}
+		if opts.AllowNonSTD3 {
+			po = append(po, idna.StrictDomainName(false))
+		}
+		if opts.CheckBidi {
+			po = append(po, idna.BidiRule())
+		}
+		n.idna = idna.New(po...)
+		return nil
+	}
+}
+
+// hostToASCII converts the host, which may have a port, to its ASCII form
+// using the IDNA profile p.
+func hostToASCII(p *idna.Profile, host string) (string, error) {
+	if host == "" || strings.HasPrefix(host, "[") {
+		return host, nil
+	}
+	var port string
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		host, port = host[:i], host[i:]
+	}
+
+	// The idna package doesn't fully conform to RFC 5895
+	// (https://tools.ietf.org/html/rfc5895), so we do it here.
+	// Taken from Go 1.8 cycle source, courtesy of bradfitz.
+	// TODO: Remove when (if?) idna package conforms to RFC 5895.
+	host = width.Fold.String(host)
+	host = norm.NFC.String(host)
+
+	ascii, err := p.ToASCII(host)
+	if err != nil {
+		label := host
+		for _, l := range strings.Split(host, ".") {
+			if _, e := p.ToASCII(l); e != nil {
+				label = l
+				break
+			}
+		}
+		return "", &IDNAError{Host: host, Label: label, Err: err}
+	}
+	return ascii + port, nil
+}
@@ -0,0 +1,76 @@
+package purell
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithIDNA(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		opts IDNAOptions
+		res  string
+	}{
+		{"Punycode", "http://Faß.de/", IDNAOptions{}, "http://xn--fa-hia.de/"},
+		{"PunycodeUnderscore", "http://a_b.com/", IDNAOptions{}, "http://a_b.com/"},
+		{"Lookup", "http://Faß.DE:8080/", IDNAOptions{Profile: IDNALookup}, "http://xn--fa-hia.de:8080/"},
+		{"Transitional", "http://Faß.de/", IDNAOptions{Profile: IDNATransitional}, "http://fass.de/"},
+		{"LookupAllowNonSTD3", "http://a_b.com/", IDNAOptions{Profile: IDNALookup, AllowNonSTD3: true}, "http://a_b.com/"},
+		{"RegistrationAllowNonSTD3", "http://ab_c.com/", IDNAOptions{Profile: IDNARegistration, AllowNonSTD3: true}, "http://ab_c.com/"},
+		{"Registration", "http://faß.de/", IDNAOptions{Profile: IDNARegistration}, "http://xn--fa-hia.de/"},
+		{"RegistrationLowercaseHost", "http://Example.COM/", IDNAOptions{Profile: IDNARegistration}, "http://example.com/"},
+		{"IPv4", "http://127.0.0.1:80/", IDNAOptions{Profile: IDNALookup}, "http://127.0.0.1/"},
+		{"IPv6", "http://[::1]/", IDNAOptions{Profile: IDNALookup}, "http://[::1]/"},
+		{"TrailingDot", "http://example.com./", IDNAOptions{Profile: IDNALookup}, "http://example.com./"},
+	}
+
+	for _, tc := range testcases {
+		n, err := NewNormalizer(FlagsSafe|FlagRemoveDefaultPort, WithIDNA(tc.opts))
+		if err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+			continue
+		}
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestWithIDNAErrors(t *testing.T) {
+	testcases := []struct {
+		nm    string
+		src   string
+		opts  IDNAOptions
+		label string
+	}{
+		{"LookupSTD3", "http://www.a_b.com/", IDNAOptions{Profile: IDNALookup}, "a_b"},
+		{"RegistrationSTD3", "http://ab_c.com/", IDNAOptions{Profile: IDNARegistration}, "ab_c"},
+		{"TransitionalSTD3", "http://a_b.com/", IDNAOptions{Profile: IDNATransitional}, "a_b"},
+		{"Bidi", "http://www.אa.com/", IDNAOptions{Profile: IDNALookup, CheckBidi: true}, "אa"},
+		{"Hyphens", "http://ab--c.com/", IDNAOptions{Profile: IDNARegistration}, "ab--c"},
+		{"Uppercase", "http://www.Faß.de/", IDNAOptions{Profile: IDNARegistration}, "Faß"},
+		{"UppercaseASCII", "http://Example.COM/", IDNAOptions{Profile: IDNARegistration}, "Example"},
+	}
+
+	for _, tc := range testcases {
+		n, err := NewNormalizer(FlagRemoveFragment, WithIDNA(tc.opts))
+		if err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+			continue
+		}
+		_, err = n.NormalizeString(tc.src)
+		var ierr *IDNAError
+		if !errors.As(err, &ierr) {
+			t.Errorf("%s - FAIL expected *IDNAError, got %v", tc.nm, err)
+		} else if ierr.Label != tc.label {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.label, ierr.Label)
+		}
+	}
+
+	if _, err := NewNormalizer(FlagsSafe, WithIDNA(IDNAOptions{Profile: 42})); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
@@ -0,0 +1,320 @@
+package purell
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// errIPv4 is returned for hosts that look like IPv4 addresses, but are not
+// valid ones.
+var errIPv4 = errors.New("invalid IPv4 address")
+
+// errIPv4Range is returned for parts of IPv4 addresses that are valid
+// numbers, but do not fit in 32 bits.
+var errIPv4Range = errors.New("out of range")
+
+// parseIPv4 parses an IPv4 address in any of the forms accepted by
+// inet_aton and web browsers: 1 to 4 dot-separated parts, each of them in
+// decimal, octal (leading 0) or hexadecimal (leading 0x). A single trailing
+// dot is allowed. It returns an error if a part is out of range.
+func parseIPv4(s string) (uint32, error) {
+	parts := strings.Split(s, ".")
+	if parts[len(parts)-1] == "" && len(parts) > 1 {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) > 4 {
+		return 0, fmt.Errorf("%w %q: too many parts", errIPv4, s)
+	}
+
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		n, _, err := parseIPv4Number(part)
+		if err != nil {
+			return 0, fmt.Errorf("%w %q: %s", errIPv4, s, err)
+		}
+		numbers[i] = n
+	}
+
+	last := len(numbers) - 1
+	var addr uint64
+	for i, n := range numbers[:last] {
+		if n > 255 {
+			return 0, fmt.Errorf("%w %q: part %d out of range", errIPv4, s, i+1)
+		}
+		addr |= n << (8 * (3 - i))
+	}
+	if numbers[last] >= 1<<(8*(4-last)) {
+		return 0, fmt.Errorf("%w %q: part %d out of range", errIPv4, s, last+1)
+	}
+	return uint32(addr + numbers[last]), nil
+}
+
+// parseIPv4Number parses a part of an IPv4 address, and returns its value
+// and radix. Values that do not fit in 32 bits are reported as out of range.
+func parseIPv4Number(s string) (uint64, int, error) {
+	if s == "" {
+		return 0, 0, errors.New("empty part")
+	}
+	part, radix := s, 10
+	if len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		s, radix = s[2:], 16
+	} else if len(s) >= 2 && s[0] == '0' {
+		s, radix = s[1:], 8
+	}
+	if s == "" {
+		return 0, radix, nil
+	}
+	n, err := strconv.ParseUint(s, radix, 32)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, radix, fmt.Errorf("part %q %w", part, errIPv4Range)
+		}
+		return 0, radix, fmt.Errorf("invalid part %q", part)
+	}
+	return n, radix, nil
+}
+
+// endsInANumber reports whether the last label of the domain is a number,
+// in which case the domain must be parsed as an IPv4 address.
+func endsInANumber(domain string) bool {
+	parts := strings.Split(domain, ".")
+	if parts[len(parts)-1] == "" {
+		if len(parts) == 1 {
+			return false
+		}
+		parts = parts[:len(parts)-1]
+	}
+	last := parts[len(parts)-1]
+	if last != "" && strings.Trim(last, "0123456789") == "" {
+		return true
+	}
+	_, _, err := parseIPv4Number(last)
+	return err == nil || errors.Is(err, errIPv4Range)
+}
+
+func formatIPv4(addr uint32) string {
+	return fmt.Sprintf("%d.%d.%d.%d", addr>>24, addr>>16&0xFF, addr>>8&0xFF, addr&0xFF)
+}
+
+// errIPv6 is returned for invalid IPv6 addresses.
+var errIPv6 = errors.New("invalid IPv6 address")
+
+// parseIPv6 parses an IPv6 address, without brackets, as per the URL
+// Standard.
+func parseIPv6(s string) ([8]uint16, error) {
+	var addr [8]uint16
+	pieceIndex, compress := 0, -1
+	p := 0
+	at := func(i int) int {
+		if i < len(s) {
+			return int(s[i])
+		}
+		return eof
+	}
+	fail := func(reason string) ([8]uint16, error) {
+		return [8]uint16{}, fmt.Errorf("%w %q: %s", errIPv6, s, reason)
+	}
+
+	if at(p) == ':' {
+		if at(p+1) != ':' {
+			return fail("unexpected leading colon")
+		}
+		p += 2
+		pieceIndex++
+		compress = pieceIndex
+	}
+	for at(p) != eof {
+		if pieceIndex == 8 {
+			return fail("too many pieces")
+		}
+		if at(p) == ':' {
+			if compress != -1 {
+				return fail("multiple compressions")
+			}
+			p++
+			pieceIndex++
+			compress = pieceIndex
+			continue
+		}
+
+		value, length := 0, 0
+		for length < 4 && at(p) != eof && isHex(byte(at(p))) {
+			value = value*16 + int(unhex(byte(at(p))))
+			p++
+			length++
+		}
+		if at(p) == '.' {
+			if length == 0 {
+				return fail("invalid IPv4 part")
+			}
+			p -= length
+			if pieceIndex > 6 {
+				return fail("IPv4 part out of place")
+			}
+			numbersSeen := 0
+			for at(p) != eof {
+				ipv4Piece := -1
+				if numbersSeen > 0 {
+					if at(p) == '.' && numbersSeen < 4 {
+						p++
+					} else {
+						return fail("invalid IPv4 part")
+					}
+				}
+				if at(p) == eof || !isASCIIDigit(rune(at(p))) {
+					return fail("invalid IPv4 part")
+				}
+				for at(p) != eof && isASCIIDigit(rune(at(p))) {
+					number := at(p) - '0'
+					switch ipv4Piece {
+					case -1:
+						ipv4Piece = number
+					case 0:
+						return fail("invalid IPv4 part")
+					default:
+						ipv4Piece = ipv4Piece*10 + number
+					}
+					if ipv4Piece > 255 {
+						return fail("IPv4 part out of range")
+					}
+					p++
+				}
+				addr[pieceIndex] = addr[pieceIndex]*0x100 + uint16(ipv4Piece)
+				numbersSeen++
+				if numbersSeen == 2 || numbersSeen == 4 {
+					pieceIndex++
+				}
+			}
+			if numbersSeen != 4 {
+				return fail("invalid IPv4 part")
+			}
+			break
+		} else if at(p) == ':' {
+			p++
+			if at(p) == eof {
+				return fail("unexpected trailing colon")
+			}
+		} else if at(p) != eof {
+			return fail(fmt.Sprintf("invalid character %q", at(p)))
+		}
+		addr[pieceIndex] = uint16(value)
+		pieceIndex++
+	}
+
+	if compress != -1 {
+		swaps := pieceIndex - compress
+		pieceIndex = 7
+		for pieceIndex != 0 && swaps > 0 {
+			addr[pieceIndex], addr[compress+swaps-1] = addr[compress+swaps-1], addr[pieceIndex]
+			pieceIndex--
+			swaps--
+		}
+	} else if pieceIndex != 8 {
+		return fail("too few pieces")
+	}
+	return addr, nil
+}
+
+// formatIPv6 serializes the IPv6 address, without brackets, compressing
+// the first longest run of zero pieces.
+func formatIPv6(addr [8]uint16) string {
+	compress, longest := -1, 1
+	for i := 0; i < 8; {
+		if addr[i] != 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < 8 && addr[j] == 0 {
+			j++
+		}
+		if j-i > longest {
+			compress, longest = i, j-i
+		}
+		i = j
+	}
+
+	var buf strings.Builder
+	for i := 0; i < 8; i++ {
+		if i == compress {
+			if i == 0 {
+				buf.WriteString("::")
+			} else {
+				buf.WriteByte(':')
+			}
+			i += longest - 1
+			continue
+		}
+		buf.WriteString(strconv.FormatUint(uint64(addr[i]), 16))
+		if i != 7 {
+			buf.WriteByte(':')
+		}
+	}
+	return buf.String()
+}
+
+// ipv4Host decodes the host, with an optional port, if it ends in a number.
+// It returns the host with its IPv4 address in dotted decimal notation,
+// keeping the trailing dots and the port, or the host unchanged if it doesn't
+// end in a number. It returns an error if the host ends in a number but is
+// not a valid IPv4 address, e.g. if a part is out of range.
+func ipv4Host(host string) (string, error) {
+	if strings.HasPrefix(host, "[") {
+		return host, nil
+	}
+	name := host
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimRight(name, ".")
+	if name == "" || !endsInANumber(name) {
+		return host, nil
+	}
+
+	addr, err := parseIPv4(name)
+	if err != nil {
+		return "", err
+	}
+	return formatIPv4(addr) + host[len(name):], nil
+}
+
+func decodeIPv4Host(u *url.URL) {
+	// Invalid addresses are reported when the URL string is parsed, the
+	// host is left untouched if the URL object is normalized directly
+	if host, err := ipv4Host(u.Host); err == nil {
+		u.Host = host
+	}
+}
+
+// formatIPv6Canonical serializes the IPv6 address, without brackets, in the
+// text representation recommended by RFC 5952. It is the same as formatIPv6,
+// except that IPv4-mapped addresses end with the IPv4 address in dotted
+// decimal notation.
+func formatIPv6Canonical(addr [8]uint16) string {
+	if addr[0]|addr[1]|addr[2]|addr[3]|addr[4] == 0 && addr[5] == 0xffff {
+		return "::ffff:" + formatIPv4(uint32(addr[6])<<16|uint32(addr[7]))
+	}
+	return formatIPv6(addr)
+}
+
+func canonicalizeIPv6Host(u *url.URL) {
+	if !strings.HasPrefix(u.Host, "[") {
+		return
+	}
+	end := strings.LastIndexByte(u.Host, ']')
+	if end < 0 {
+		return
+	}
+
+	// The zone identifier, if any, is kept untouched
+	literal, zone := u.Host[1:end], ""
+	if i := strings.IndexByte(literal, '%'); i >= 0 {
+		literal, zone = literal[:i], literal[i:]
+	}
+	if addr, err := parseIPv6(literal); err == nil {
+		u.Host = "[" + formatIPv6Canonical(addr) + zone + u.Host[end:]
+	}
+}
@@ -0,0 +1,194 @@
+package purell
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeURLStringIRI is like NormalizeURLString, but returns the
+// normalized URL as an IRI, see URIToIRI.
+func NormalizeURLStringIRI(u string, f NormalizationFlags) (string, error) {
+	s, err := NormalizeURLString(u, f)
+	if err != nil {
+		return "", err
+	}
+	return URIToIRI(s), nil
+}
+
+// WithIRI makes the Normalizer return the normalized URLs as IRIs, see
+// URIToIRI.
+func WithIRI() Option {
+	return func(n *Normalizer) error {
+		n.iri = true
+		return nil
+	}
+}
+
+// URIToIRI converts the URI string to an IRI (RFC 3987), for display. The
+// Punycode labels of the host are converted to Unicode, and the
+// percent-encoded UTF-8 sequences of the path, query and fragment are
+// decoded, unless they encode characters that are not allowed in IRIs or
+// that could be confusing, such as spaces and Bidi formatting characters.
+// Non-ASCII characters outside of the host, which the normalization
+// functions keep as is in the query and in opaque URIs, are percent-encoded
+// first.
+//
+// A label or sequence is only converted if IRIToURI converts it back
+// exactly, so that IRIToURI(URIToIRI(uri)) returns uri with its non-ASCII
+// characters percent-encoded, that is uri itself if it is ASCII.
+func URIToIRI(uri string) string {
+	before, host, after := splitHost(uri)
+	return encodeNonASCII(before) + hostToUnicode(host) + decodeIRIEscapes(encodeNonASCII(after))
+}
+
+// IRIToURI converts the IRI string to an URI: the host is converted to its
+// ASCII form as done by the normalization functions, and the non-ASCII
+// characters are percent-encoded. It returns an error if the host can't be
+// converted.
+func IRIToURI(iri string) (string, error) {
+	before, host, after := splitHost(iri)
+	if !isASCII(host) {
+		var err error
+		if host, err = hostToASCII(idna.Punycode, host); err != nil {
+			return "", err
+		}
+	}
+	return encodeNonASCII(before) + host + encodeNonASCII(after), nil
+}
+
+// splitHost splits the URI or IRI string around its host, without the
+// userinfo and port. The host is empty if there is no authority.
+func splitHost(s string) (before, host, after string) {
+	start := strings.Index(s, "//")
+	if start < 0 || (start > 0 && (s[start-1] != ':' || strings.ContainsAny(s[:start], "/?#"))) {
+		return "", "", s
+	}
+	start += 2
+	end := len(s)
+	if i := strings.IndexAny(s[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	if i := strings.LastIndexByte(s[start:end], '@'); i >= 0 {
+		start += i + 1
+	}
+	if i := strings.LastIndexByte(s[start:end], ':'); i >= 0 && !strings.HasPrefix(s[start:], "[") {
+		end = start + i
+	}
+	return s[:start], s[start:end], s[end:]
+}
+
+// hostToUnicode converts the Punycode labels of the host to Unicode, if
+// they only contain characters allowed in IRIs and hostToASCII converts
+// them back exactly.
+func hostToUnicode(host string) string {
+	if !strings.Contains(host, "xn--") {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, "xn--") {
+			continue
+		}
+		u, err := idna.ToUnicode(label)
+		if err != nil || u == label || strings.IndexFunc(u, func(r rune) bool {
+			return r >= utf8.RuneSelf && !isIRIChar(r)
+		}) >= 0 {
+			continue
+		}
+		if back, err := hostToASCII(idna.Punycode, u); err == nil && back == label {
+			labels[i] = u
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// decodeIRIEscapes decodes the percent-encoded UTF-8 sequences of
+// non-ASCII characters allowed in IRIs. Sequences are only decoded if
+// their hexadecimal digits are uppercase, as written back by
+// encodeNonASCII.
+func decodeIRIEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); {
+		if r, n := decodeEscapedRune(s[i:]); n > 0 && isIRIChar(r) {
+			buf.WriteRune(r)
+			i += n
+			continue
+		}
+		buf.WriteByte(s[i])
+		i++
+	}
+	return buf.String()
+}
+
+// decodeEscapedRune decodes the percent-encoded UTF-8 sequence of a
+// non-ASCII character at the start of s, and returns its length in s, or
+// zero if there is none.
+func decodeEscapedRune(s string) (rune, int) {
+	var b [utf8.UTFMax]byte
+	n := 0
+	for ; n < len(b) && 3*n+2 < len(s) && s[3*n] == '%'; n++ {
+		if !isUpperHex(s[3*n+1]) || !isUpperHex(s[3*n+2]) {
+			break
+		}
+		b[n] = unhex(s[3*n+1])<<4 | unhex(s[3*n+2])
+		if n == 0 && b[0] < utf8.RuneSelf {
+			return 0, 0
+		}
+		if utf8.FullRune(b[:n+1]) {
+			r, size := utf8.DecodeRune(b[:n+1])
+			if r == utf8.RuneError && size <= 1 {
+				return 0, 0
+			}
+			return r, 3 * size
+		}
+	}
+	return 0, 0
+}
+
+func isUpperHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'A' <= c && c <= 'F'
+}
+
+// isIRIChar returns true if the character is allowed in IRIs (the ucschar
+// production of RFC 3987) and is not a space, control or formatting
+// character, such as the Bidi formatting characters.
+func isIRIChar(r rune) bool {
+	switch {
+	case r < 0xA0, r >= 0xD800 && r <= 0xF8FF, r >= 0xFDD0 && r <= 0xFDEF, r&0xFFFE == 0xFFFE, r >= 0xE0000 && r < 0xE1000, r > 0xEFFFD:
+		return false
+	}
+	return !unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zs, unicode.Zl, unicode.Zp)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeNonASCII percent-encodes the non-ASCII bytes of the string.
+func encodeNonASCII(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= utf8.RuneSelf {
+			buf.WriteByte('%')
+			buf.WriteByte("0123456789ABCDEF"[c>>4])
+			buf.WriteByte("0123456789ABCDEF"[c&15])
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
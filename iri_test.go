@@ -0,0 +1,111 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestURIToIRI(t *testing.T) {
+	testcases := []struct {
+		nm  string
+		src string
+		res string
+	}{
+		{"Host", "http://xn--caf-dma.com/", "http://café.com/"},
+		{"HostPort", "http://user@xn--caf-dma.com:8080/", "http://user@café.com:8080/"},
+		{"Path", "http://host/caf%C3%A9", "http://host/café"},
+		{"QueryFragment", "http://host/?q=%E2%82%AC#%C3%A9t%C3%A9", "http://host/?q=€#été"},
+		{"ASCIIEscapes", "http://host/a%20b%2Fc?a=%26", "http://host/a%20b%2Fc?a=%26"},
+		{"LowercaseEscapes", "http://host/caf%c3%a9", "http://host/caf%c3%a9"},
+		{"InvalidUTF8", "http://host/%C3%28%E2%82", "http://host/%C3%28%E2%82"},
+		{"Bidi", "http://host/a%E2%80%8Fb", "http://host/a%E2%80%8Fb"},
+		{"NoBreakSpace", "http://host/a%C2%A0b", "http://host/a%C2%A0b"},
+		{"Userinfo", "http://%C3%A9@host/", "http://%C3%A9@host/"},
+		{"InvalidPunycode", "http://xn--a.com/", "http://xn--a.com/"},
+		{"IPv6", "http://[::1]:80/%C3%A9", "http://[::1]:80/é"},
+		{"Opaque", "mailto:caf%C3%A9@example.com", "mailto:café@example.com"},
+		{"Relative", "/caf%C3%A9?//xn--caf-dma.com", "/café?//xn--caf-dma.com"},
+	}
+
+	for _, tc := range testcases {
+		if got := URIToIRI(tc.src); got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+		if got, err := IRIToURI(tc.res); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.src {
+			t.Errorf("%s - FAIL round-trip expected '%s', got '%s'", tc.nm, tc.src, got)
+		}
+	}
+}
+
+func TestIRIRoundTrip(t *testing.T) {
+	type roundTripCase struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+	}
+	var testcases []roundTripCase
+	for _, tc := range cases {
+		testcases = append(testcases, roundTripCase{tc.nm, tc.src, tc.flgs})
+	}
+	testcases = append(testcases,
+		roundTripCase{"RawQuery", "http://h/a?q=é", FlagsSafe},
+		roundTripCase{"RawQueryNotIRIChar", "http://h/a?q=a\u200fb", FlagsSafe},
+		roundTripCase{"RawOpaque", "mailto:josé@example.com", FlagsSafe},
+		roundTripCase{"RawFragment", "http://h/#é", FlagsSafe},
+	)
+
+	for _, tc := range testcases {
+		s, err := NormalizeURLString(tc.src, tc.flgs)
+		if err != nil {
+			continue
+		}
+		// The normalized hosts are ASCII, only the other parts may need to
+		// be encoded.
+		want := encodeNonASCII(s)
+
+		iri, err := NormalizeURLStringIRI(tc.src, tc.flgs)
+		if err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+			continue
+		}
+		if got := MustNewNormalizer(tc.flgs, WithIRI()).MustNormalizeString(tc.src); got != iri {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, iri, got)
+		}
+		if got, err := IRIToURI(iri); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != want {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, want, got)
+		}
+		if got, err := IRIToURI(URIToIRI(want)); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != want {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, want, got)
+		}
+	}
+}
+
+func TestNormalizeURLStringIRI(t *testing.T) {
+	const want = "http://café.com/été?q=€"
+	got, err := NormalizeURLStringIRI("HTTP://CAFÉ.com:80/%C3%A9t%C3%A9?q=€", FlagsSafe|FlagRemoveDefaultPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+
+	n := MustNewNormalizer(FlagsSafe|FlagRemoveDefaultPort, WithIRI())
+	if got := n.MustNormalizeString("HTTP://CAFÉ.com:80/%C3%A9t%C3%A9?q=€"); got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+
+	// Raw non-ASCII characters kept by the normalization are encoded, and
+	// only decoded back if they are allowed in IRIs.
+	if got := MustNormalizeURLString("http://h/a?q=\u200fé", FlagsSafe); got != "http://h/a?q=\u200fé" {
+		t.Fatalf("expected the query to be kept as is, got '%s'", got)
+	}
+	if got, _ := NormalizeURLStringIRI("http://h/a?q=\u200fé", FlagsSafe); got != "http://h/a?q=%E2%80%8Fé" {
+		t.Errorf("expected 'http://h/a?q=%%E2%%80%%8Fé', got '%s'", got)
+	}
+}
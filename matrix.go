@@ -0,0 +1,96 @@
+package purell
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// matrixFlags are the flags that split the path on its semicolons, so that
+// its escaped semicolons must be kept.
+const matrixFlags = FlagRemoveSessionIDs | FlagRemoveMatrixParams | FlagSortMatrixParams
+
+// WithMatrixParams sets the names and patterns of the matrix parameters
+// kept by FlagRemoveMatrixParams, the other ones are removed. Patterns use
+// the syntax of path.Match, and names are matched case-sensitively.
+func WithMatrixParams(patterns ...string) Option {
+	return func(n *Normalizer) error {
+		m, err := newParamMatcher(patterns)
+		if err != nil {
+			return err
+		}
+		n.funcs[FlagRemoveMatrixParams] = func(u *url.URL) {
+			filterMatrixParams(u, func(p string) bool {
+				return m.match(matrixParamName(p))
+			})
+		}
+		return nil
+	}
+}
+
+// mapMatrixParams replaces the ;name=value parameters of each segment of
+// the path by the result of fn. The path is split in its escaped form, so
+// that escaped slashes and semicolons are kept as data, and the parameters
+// are passed to fn escaped.
+func mapMatrixParams(u *url.URL, fn func(params []string) []string) {
+	if !strings.Contains(u.Path, ";") {
+		return
+	}
+
+	escaped := u.EscapedPath()
+	segments := strings.Split(escaped, "/")
+	for i, seg := range segments {
+		parts := strings.Split(seg, ";")
+		if len(parts) == 1 {
+			continue
+		}
+		params := fn(parts[1:])
+		segments[i] = strings.Join(append(parts[:1], params...), ";")
+	}
+	if p := strings.Join(segments, "/"); p != escaped {
+		if path, err := url.PathUnescape(p); err == nil {
+			u.Path, u.RawPath = path, p
+		}
+	}
+}
+
+// filterMatrixParams removes the matrix parameters for which keep returns
+// false.
+func filterMatrixParams(u *url.URL, keep func(string) bool) {
+	mapMatrixParams(u, func(params []string) []string {
+		kept := params[:0]
+		for _, p := range params {
+			if keep(p) {
+				kept = append(kept, p)
+			}
+		}
+		return kept
+	})
+}
+
+func matrixParamName(p string) string {
+	name, _, _ := strings.Cut(p, "=")
+	return name
+}
+
+func removeMatrixParams(u *url.URL) {
+	filterMatrixParams(u, func(string) bool { return false })
+}
+
+// sortMatrixParams sorts the matrix parameters of each segment by name,
+// keeping the relative order of the parameters of the same name. Empty
+// parameters are removed.
+func sortMatrixParams(u *url.URL) {
+	mapMatrixParams(u, func(params []string) []string {
+		kept := params[:0]
+		for _, p := range params {
+			if p != "" {
+				kept = append(kept, p)
+			}
+		}
+		sort.SliceStable(kept, func(i, j int) bool {
+			return matrixParamName(kept[i]) < matrixParamName(kept[j])
+		})
+		return kept
+	})
+}
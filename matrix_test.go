@@ -0,0 +1,128 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestMatrixParams(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+		res  string
+	}{
+		{
+			"Remove",
+			"http://host/a;v=1/b;c=2;d/c?x=1;y",
+			FlagRemoveMatrixParams,
+			"http://host/a/b/c?x=1;y",
+		},
+		{
+			"RemoveEmpty",
+			"http://host/a;/b;;",
+			FlagRemoveMatrixParams,
+			"http://host/a/b",
+		},
+		{
+			"Sort",
+			"http://host/a;v=1;b=2/c",
+			FlagSortMatrixParams,
+			"http://host/a;b=2;v=1/c",
+		},
+		{
+			"SortEquivalent",
+			"http://host/a;b=2;v=1/c",
+			FlagSortMatrixParams,
+			"http://host/a;b=2;v=1/c",
+		},
+		{
+			"SortStable",
+			"http://host/a;v=2;b;v=1;;a=3",
+			FlagSortMatrixParams,
+			"http://host/a;a=3;b;v=2;v=1",
+		},
+		{
+			"SortEscaped",
+			"http://host/a%20b;z=%20;a=%C3%A9",
+			FlagSortMatrixParams,
+			"http://host/a%20b;a=%C3%A9;z=%20",
+		},
+		{
+			"SortEscapedSemicolon",
+			"http://host/a;b=%3B;a=1",
+			FlagSortMatrixParams,
+			"http://host/a;a=1;b=%3B",
+		},
+		{
+			"RemoveEscapedSemicolon",
+			"http://host/a%3Bv=1/c;x=2",
+			FlagRemoveMatrixParams,
+			"http://host/a%3Bv=1/c",
+		},
+		{
+			"RemoveEscapedSlash",
+			"http://host/a;v=%2F1/c",
+			FlagRemoveMatrixParams,
+			"http://host/a/c",
+		},
+		{
+			"BeforeDirectoryIndex",
+			"http://host/index.html;v=1",
+			FlagRemoveMatrixParams | FlagRemoveDirectoryIndex,
+			"http://host/",
+		},
+		{
+			"DotSegments",
+			"http://host/a;v=1/../b;x=2",
+			FlagRemoveMatrixParams | FlagRemoveDotSegments,
+			"http://host/b",
+		},
+		{
+			"NotSet",
+			"http://host/a;v=1;b=2/c",
+			FlagsAllGreedy,
+			"http://host/a;v=1;b=2/c",
+		},
+	}
+
+	for _, tc := range testcases {
+		if got, err := NormalizeURLString(tc.src, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestWithMatrixParams(t *testing.T) {
+	n, err := NewNormalizer(FlagRemoveMatrixParams|FlagSortMatrixParams, WithMatrixParams("v", "lang_*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		src string
+		res string
+	}{
+		{"http://host/a;x=1;v=1;lang_en/c;b=2", "http://host/a;lang_en;v=1/c"},
+		{"http://host/a;v=1;lang_en/c", "http://host/a;lang_en;v=1/c"},
+		{"http://host/a;V=1", "http://host/a"},
+	}
+	for _, tc := range testcases {
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.src, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.src, tc.res, got)
+		}
+	}
+
+	n = MustNewNormalizer(FlagSortMatrixParams, WithWHATWG())
+	if got, err := n.NormalizeString("http://host/a;b=%3B;a=1"); err != nil {
+		t.Errorf("WHATWG - FAIL : %s", err)
+	} else if want := "http://host/a;a=1;b=%3B"; got != want {
+		t.Errorf("WHATWG - FAIL expected '%s', got '%s'", want, got)
+	}
+
+	if _, err := NewNormalizer(FlagRemoveMatrixParams, WithMatrixParams("v[")); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
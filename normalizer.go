@@ -0,0 +1,301 @@
+package purell
+
+import (
+	"fmt"
+	"net/url"
+
+	"golang.org/x/net/idna"
+)
+
+// A Step is a custom normalization step that can be added to a Normalizer.
+//
+// Built-in steps are named after their flag, in lowercase words separated by
+// dashes and without the Flag prefix (e.g. FlagRemoveTrailingSlash is
+// "remove-trailing-slash"), so that custom steps can be ordered relative to
+// them. A constraint on a built-in step holds even if its flag is not set.
+// FlagUppercaseEscapes, FlagDecodeUnnecessaryEscapes,
+// FlagEncodeNecessaryEscapes and FlagRemoveEmptyQuerySeparator have no step:
+// they are applied when the URL is parsed and serialized, so steps can't be
+// ordered relative to them.
+type Step struct {
+	// Name identifies the step, it must be unique within a Normalizer.
+	Name string
+
+	// Func applies the step to the URL, modifying it in place.
+	Func func(*url.URL)
+
+	// Before lists the names of the steps that must run after this one.
+	Before []string
+
+	// After lists the names of the steps that must run before this one.
+	After []string
+}
+
+// An Option configures a Normalizer.
+type Option func(*Normalizer) error
+
+// WithStep adds a custom step to the Normalizer. Steps without ordering
+// constraints run after all built-in steps, in the order they were added.
+func WithStep(s Step) Option {
+	return func(n *Normalizer) error {
+		if s.Name == "" {
+			return fmt.Errorf("purell: step has no name")
+		}
+		if s.Func == nil {
+			return fmt.Errorf("purell: step %q has no function", s.Name)
+		}
+		n.custom = append(n.custom, s)
+		return nil
+	}
+}
+
+// A Normalizer normalizes URLs using a set of normalization flags combined
+// with custom steps. It is safe for concurrent use once created.
+type Normalizer struct {
+	flags  NormalizationFlags
+	funcs  map[NormalizationFlags]func(*url.URL) // built-in steps configured by options
+	before map[NormalizationFlags][]step         // steps added by options, that run right before a flag's step
+	custom []Step
+	steps  []step
+	whatwg bool          // parse and serialize as per the WHATWG URL Standard
+	iri    bool          // serialize as an IRI
+	idna   *idna.Profile // converts the host to its ASCII form
+
+	queryRules []queryRule // rules of WithQueryRules, in order
+
+	homograph     bool // check the hosts for homographs
+	homographWarn func(string, error)
+}
+
+// step is a resolved normalization step of a Normalizer. The flag is zero
+// for custom steps.
+type step struct {
+	name string
+	flag NormalizationFlags
+	fn   func(*url.URL)
+}
+
+// NewNormalizer returns a Normalizer that applies the normalizations
+// specified by the flags and the options. It returns an error if a custom
+// step is invalid, or if the ordering constraints cannot be satisfied.
+func NewNormalizer(f NormalizationFlags, opts ...Option) (*Normalizer, error) {
+	n := &Normalizer{
+		flags:  f,
+		funcs:  make(map[NormalizationFlags]func(*url.URL)),
+		before: make(map[NormalizationFlags][]step),
+		idna:   idna.Punycode,
+	}
+	for _, opt := range opts {
+		if err := opt(n); err != nil {
+			return nil, err
+		}
+	}
+
+	steps, err := n.orderSteps()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range steps {
+		if s.flag == 0 || f&s.flag == s.flag {
+			n.steps = append(n.steps, s)
+		}
+	}
+	return n, nil
+}
+
+// MustNewNormalizer is like NewNormalizer but panics if an error occurs.
+func MustNewNormalizer(f NormalizationFlags, opts ...Option) *Normalizer {
+	n, err := NewNormalizer(f, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Flags returns the normalization flags of the Normalizer.
+func (n *Normalizer) Flags() NormalizationFlags {
+	return n.flags
+}
+
+// NormalizeString returns the normalized string, or an error if it can't be
+// parsed into an URL object. It is the equivalent of NormalizeURLString.
+func (n *Normalizer) NormalizeString(u string) (string, error) {
+	parsed, x, err := n.parse(u)
+	if err != nil {
+		return "", err
+	}
+	n.apply(parsed)
+	return n.serialize(parsed, x), nil
+}
+
+// MustNormalizeString returns the normalized string, and panics if an error
+// occurs.
+func (n *Normalizer) MustNormalizeString(u string) string {
+	result, err := n.NormalizeString(u)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// NormalizeURL returns the normalized string. Like the NormalizeURL function,
+// it modifies the URL object.
+func (n *Normalizer) NormalizeURL(u *url.URL) string {
+	n.apply(u)
+	return n.serialize(u, whatwgExtra{})
+}
+
+// NormalizedURL returns a normalized copy of the URL object, leaving it
+// untouched. It is the equivalent of the NormalizedURL function.
+func (n *Normalizer) NormalizedURL(u *url.URL) *url.URL {
+	clone := cloneURL(u)
+	return reparseURL(n.NormalizeURL(clone), clone)
+}
+
+// parse parses the URL string as expected by the steps of the Normalizer.
+func (n *Normalizer) parse(u string) (*url.URL, whatwgExtra, error) {
+	var (
+		parsed *url.URL
+		x      whatwgExtra
+	)
+	if n.whatwg {
+		w, err := parseWHATWG(u, nil)
+		if err != nil {
+			return nil, whatwgExtra{}, err
+		}
+		parsed, x = w.toURL()
+	} else {
+		var err error
+		if parsed, err = parseURL(u, n.flags, n.idna); err != nil {
+			return nil, whatwgExtra{}, err
+		}
+	}
+
+	if err := n.checkHomograph(u, parsed.Hostname()); err != nil {
+		return nil, whatwgExtra{}, err
+	}
+	return parsed, x, nil
+}
+
+// serialize returns the URL string of the URL object.
+func (n *Normalizer) serialize(u *url.URL, x whatwgExtra) string {
+	var s string
+	if n.whatwg {
+		s = serializeWHATWG(u, n.flags, x)
+	} else {
+		s = escapeURLSemicolons(u, n.flags&matrixFlags != 0)
+	}
+	if n.iri {
+		s = URIToIRI(s)
+	}
+	return s
+}
+
+// apply runs the steps of the Normalizer on the URL object.
+func (n *Normalizer) apply(u *url.URL) {
+	for _, s := range n.steps {
+		s.fn(u)
+	}
+}
+
+// insertBefore adds a built-in step that runs right before the step of the
+// flag, whether that flag is set or not.
+func (n *Normalizer) insertBefore(f NormalizationFlags, s step) {
+	n.before[f] = append(n.before[f], s)
+}
+
+// builtinSteps returns all built-in steps in their default order, whether
+// their flag is set or not.
+func (n *Normalizer) builtinSteps() []step {
+	steps := make([]step, 0, len(flagsOrder))
+	for _, k := range flagsOrder {
+		steps = append(steps, n.before[k]...)
+		fn, ok := n.funcs[k]
+		if !ok {
+			fn = flags[k]
+		}
+		steps = append(steps, step{name: flagNames[k], flag: k, fn: fn})
+	}
+	return steps
+}
+
+// orderSteps merges the custom steps with the built-in ones, honouring the
+// ordering constraints. Ties are broken by keeping the built-in steps in
+// their default order, followed by the custom steps in the order they were
+// added.
+func (n *Normalizer) orderSteps() ([]step, error) {
+	steps := n.builtinSteps()
+	builtin := len(steps)
+	for _, s := range n.custom {
+		steps = append(steps, step{name: s.Name, fn: s.Func})
+	}
+
+	index := make(map[string]int, len(steps))
+	for i, s := range steps {
+		if _, ok := index[s.name]; ok {
+			return nil, fmt.Errorf("purell: duplicate step name %q", s.name)
+		}
+		index[s.name] = i
+	}
+
+	// edges[i] lists the steps that must run after step i
+	edges := make([][]int, len(steps))
+	indegree := make([]int, len(steps))
+	addEdge := func(from, to int) {
+		edges[from] = append(edges[from], to)
+		indegree[to]++
+	}
+	for i := 1; i < builtin; i++ {
+		addEdge(i-1, i)
+	}
+	for i, s := range n.custom {
+		i += builtin
+		for _, name := range s.Before {
+			j, ok := index[name]
+			if !ok {
+				return nil, unknownStepError(s.Name, "before", name)
+			}
+			addEdge(i, j)
+		}
+		for _, name := range s.After {
+			j, ok := index[name]
+			if !ok {
+				return nil, unknownStepError(s.Name, "after", name)
+			}
+			addEdge(j, i)
+		}
+	}
+
+	ordered := make([]step, 0, len(steps))
+	done := make([]bool, len(steps))
+	for len(ordered) < len(steps) {
+		next := -1
+		for i := range steps {
+			if !done[i] && indegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("purell: cyclic ordering constraints between steps")
+		}
+		done[next] = true
+		ordered = append(ordered, steps[next])
+		for _, j := range edges[next] {
+			indegree[j]--
+		}
+	}
+	return ordered, nil
+}
+
+// unknownStepError returns the error for a step that must run before or
+// after an unknown step. Flags applied when the URL is parsed and serialized
+// have a name but no step, they get a specific message.
+func unknownStepError(name, rel, unknown string) error {
+	for k, v := range flagNames {
+		if _, ok := flags[k]; !ok && v == unknown {
+			return fmt.Errorf("purell: step %q can't run %s %q, which is applied when the URL is parsed and serialized", name, rel, unknown)
+		}
+	}
+	return fmt.Errorf("purell: step %q must run %s unknown step %q", name, rel, unknown)
+}
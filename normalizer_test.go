@@ -0,0 +1,190 @@
+package purell
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNormalizerMatchesFunctions(t *testing.T) {
+	for _, tc := range cases {
+		n, err := NewNormalizer(tc.flgs)
+		if err != nil {
+			t.Fatalf("%s - FAIL : %s", tc.nm, err)
+		}
+		want, err := NormalizeURLString(tc.src, tc.flgs)
+		if err != nil {
+			t.Fatalf("%s - FAIL : %s", tc.nm, err)
+		}
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != want {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, want, got)
+		}
+	}
+}
+
+func appendPath(s string) func(*url.URL) {
+	return func(u *url.URL) {
+		u.Path += s
+	}
+}
+
+func TestNormalizerSteps(t *testing.T) {
+	testcases := []struct {
+		nm    string
+		flgs  NormalizationFlags
+		steps []Step
+		src   string
+		res   string
+	}{
+		{
+			"NoConstraint",
+			FlagRemoveTrailingSlash,
+			[]Step{{Name: "a", Func: appendPath("/a/")}},
+			"http://host/path/",
+			"http://host/path/a/",
+		},
+		{
+			"Before",
+			FlagRemoveTrailingSlash,
+			[]Step{{Name: "a", Func: appendPath("/a/"), Before: []string{"remove-trailing-slash"}}},
+			"http://host/path/",
+			"http://host/path//a",
+		},
+		{
+			"BeforeUnsetFlag",
+			FlagSortQuery,
+			[]Step{{Name: "a", Func: func(u *url.URL) { u.RawQuery += "&a=1" }, Before: []string{"remove-trailing-slash"}}},
+			"http://host/?b=2",
+			"http://host/?b=2&a=1",
+		},
+		{
+			"BeforeSortQuery",
+			FlagSortQuery,
+			[]Step{{Name: "a", Func: func(u *url.URL) { u.RawQuery += "&a=1" }, Before: []string{"sort-query"}}},
+			"http://host/?b=2",
+			"http://host/?a=1&b=2",
+		},
+		{
+			"InsertionOrder",
+			0,
+			[]Step{
+				{Name: "a", Func: appendPath("a")},
+				{Name: "b", Func: appendPath("b")},
+			},
+			"http://host/",
+			"http://host/ab",
+		},
+		{
+			"CustomConstraints",
+			0,
+			[]Step{
+				{Name: "a", Func: appendPath("a"), After: []string{"b"}},
+				{Name: "b", Func: appendPath("b")},
+				{Name: "c", Func: appendPath("c"), Before: []string{"b"}},
+			},
+			"http://host/",
+			"http://host/cba",
+		},
+		{
+			"FirstStep",
+			FlagLowercaseScheme,
+			[]Step{{Name: "a", Func: func(u *url.URL) { u.Scheme = "HTTPS" }, Before: []string{"lowercase-scheme"}}},
+			"http://host/",
+			"https://host/",
+		},
+	}
+
+	for _, tc := range testcases {
+		var opts []Option
+		for _, s := range tc.steps {
+			opts = append(opts, WithStep(s))
+		}
+		n, err := NewNormalizer(tc.flgs, opts...)
+		if err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+			continue
+		}
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestNormalizerErrors(t *testing.T) {
+	noop := func(*url.URL) {}
+	testcases := []struct {
+		nm    string
+		steps []Step
+		err   string
+	}{
+		{"NoName", []Step{{Func: noop}}, "no name"},
+		{"NoFunc", []Step{{Name: "a"}}, "no function"},
+		{"Duplicate", []Step{{Name: "a", Func: noop}, {Name: "a", Func: noop}}, "duplicate"},
+		{"DuplicateBuiltin", []Step{{Name: "sort-query", Func: noop}}, "duplicate"},
+		{"UnknownBefore", []Step{{Name: "a", Func: noop, Before: []string{"b"}}}, "unknown step"},
+		{"UnknownAfter", []Step{{Name: "a", Func: noop, After: []string{"b"}}}, "unknown step"},
+		{"SerializationBefore", []Step{{Name: "a", Func: noop, Before: []string{"uppercase-escapes"}}}, "applied when the URL is parsed and serialized"},
+		{"SerializationAfter", []Step{{Name: "a", Func: noop, After: []string{"remove-empty-query-separator"}}}, "applied when the URL is parsed and serialized"},
+		{"Cycle", []Step{
+			{Name: "a", Func: noop, Before: []string{"b"}},
+			{Name: "b", Func: noop, Before: []string{"a"}},
+		}, "cyclic"},
+		{"CycleBuiltin", []Step{
+			{Name: "a", Func: noop, Before: []string{"lowercase-scheme"}, After: []string{"sort-query"}},
+		}, "cyclic"},
+	}
+
+	for _, tc := range testcases {
+		var opts []Option
+		for _, s := range tc.steps {
+			opts = append(opts, WithStep(s))
+		}
+		if _, err := NewNormalizer(FlagsSafe, opts...); err == nil {
+			t.Errorf("%s - FAIL expected error", tc.nm)
+		} else if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s - FAIL expected error containing '%s', got '%s'", tc.nm, tc.err, err)
+		}
+	}
+}
+
+func TestNormalizerConcurrent(t *testing.T) {
+	n := MustNewNormalizer(FlagsAllGreedy)
+	want := MustNormalizeURLString(allCombinedUrl, FlagsAllGreedy)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := n.MustNormalizeString(allCombinedUrl); got != want {
+					t.Errorf("expected '%s', got '%s'", want, got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNormalizerNormalizedURL(t *testing.T) {
+	n := MustNewNormalizer(FlagsUsuallySafeGreedy|FlagSortQuery, WithStep(Step{Name: "a", Func: appendPath("/a")}))
+	u, _ := url.Parse("HTTP://Host:80/caf%c3%a9/./b/?b=2&a=%7e#frag")
+	orig := u.String()
+
+	got := n.NormalizedURL(u)
+	if want := "http://host/caf%C3%A9/b/a?a=~&b=2#frag"; got.String() != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, got)
+	}
+	if got.Path != "/café/b/a" || got.RawQuery != "a=~&b=2" || got.Host != "host" {
+		t.Errorf("FAIL expected consistent fields, got %#v", got)
+	}
+	if u.String() != orig {
+		t.Errorf("FAIL expected the URL object to be untouched '%s', got '%s'", orig, u)
+	}
+}
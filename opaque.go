@@ -0,0 +1,262 @@
+package purell
+
+import (
+	"mime"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// This file implements the built-in scheme-specific normalizations of
+// opaque URIs, see FlagSchemeSpecific.
+
+// upperEscapes uppercases the hexadecimal digits of the percent-encoded
+// octets.
+func upperEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	b := []byte(s)
+	for i := 0; i+2 < len(b); i++ {
+		if b[i] == '%' && isHex(b[i+1]) && isHex(b[i+2]) {
+			b[i+1], b[i+2] = upperHex(b[i+1]), upperHex(b[i+2])
+			i += 2
+		}
+	}
+	return string(b)
+}
+
+func upperHex(c byte) byte {
+	if 'a' <= c && c <= 'f' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// lowerKeepEscapes lowercases the string, except for the hexadecimal digits
+// of the percent-encoded octets.
+func lowerKeepEscapes(s string) string {
+	return upperEscapes(strings.ToLower(s))
+}
+
+// normalizeMailto lowercases the domain of the addresses of a mailto: URI
+// (RFC 6068), merges the addresses of the "to" header into the path,
+// removes duplicate addresses, and sorts the other headers by name, the
+// "cc" and "bcc" headers being merged into one.
+func normalizeMailto(u *url.URL) {
+	if u.Opaque == "" && u.Path != "" {
+		return
+	}
+
+	to := newMailtoAddresses(u.Opaque)
+	var headers []queryParam
+	merged := make(map[string]*mailtoAddresses)
+	for _, p := range parseQuery(u.RawQuery) {
+		name, value, hasValue := strings.Cut(p.raw, "=")
+		name = lowerKeepEscapes(name)
+		switch name {
+		case "to":
+			to.add(value)
+		case "cc", "bcc":
+			if addrs, ok := merged[name]; ok {
+				addrs.add(value)
+				continue
+			}
+			merged[name] = newMailtoAddresses(value)
+			headers = append(headers, queryParam{key: name})
+		default:
+			raw := name
+			if hasValue {
+				raw += "=" + value
+			}
+			headers = append(headers, queryParam{key: name, raw: raw})
+		}
+	}
+
+	sort.SliceStable(headers, func(i, j int) bool {
+		return headers[i].key < headers[j].key
+	})
+	for i, h := range headers {
+		if addrs, ok := merged[h.key]; ok {
+			headers[i].raw = h.key + "=" + addrs.String()
+		}
+	}
+	u.Opaque = to.String()
+	u.RawQuery = formatQuery(headers)
+	if u.RawQuery == "" {
+		u.ForceQuery = false
+	}
+}
+
+// mailtoAddresses is a list of unique addresses of a mailto: URI.
+type mailtoAddresses struct {
+	list []string
+	seen map[string]bool
+}
+
+func newMailtoAddresses(s string) *mailtoAddresses {
+	addrs := &mailtoAddresses{seen: make(map[string]bool)}
+	addrs.add(s)
+	return addrs
+}
+
+// add adds the comma-separated addresses, with the domain lowercased.
+func (addrs *mailtoAddresses) add(s string) {
+	s = strings.NewReplacer("%2C", ",", "%2c", ",").Replace(s)
+	for _, addr := range strings.Split(s, ",") {
+		if addr == "" {
+			continue
+		}
+		i, sep := strings.LastIndex(addr, "@"), 1
+		if i < 0 {
+			i, sep = strings.LastIndex(strings.ToLower(addr), "%40"), 3
+		}
+		if i >= 0 {
+			addr = addr[:i+sep] + lowerKeepEscapes(addr[i+sep:])
+		}
+		if !addrs.seen[addr] {
+			addrs.seen[addr] = true
+			addrs.list = append(addrs.list, addr)
+		}
+	}
+}
+
+func (addrs *mailtoAddresses) String() string {
+	return strings.Join(addrs.list, ",")
+}
+
+// normalizeData canonicalizes the media type of a data: URI (RFC 2397):
+// lowercase type and parameter names, lowercase charset, parameters sorted
+// by name, and no media type at all for the default
+// text/plain;charset=US-ASCII. The data itself is left untouched.
+func normalizeData(u *url.URL) {
+	i := strings.IndexByte(u.Opaque, ',')
+	if i < 0 {
+		return
+	}
+	mediaType, data := u.Opaque[:i], u.Opaque[i:]
+	var base64 string
+	if j := strings.LastIndexByte(mediaType, ';'); j >= 0 && strings.EqualFold(mediaType[j+1:], "base64") {
+		mediaType, base64 = mediaType[:j], ";base64"
+	}
+	if mt, ok := canonicalMediaType(mediaType); ok {
+		u.Opaque = mt + base64 + data
+	}
+}
+
+// canonicalMediaType returns the canonical form of the media type of a
+// data: URI, or false if it is invalid or can't be written back without
+// quoting.
+func canonicalMediaType(s string) (string, bool) {
+	if s == "" || strings.HasPrefix(s, ";") {
+		s = "text/plain" + s
+	}
+	if strings.Contains(s, "%") {
+		return "", false
+	}
+	typ, params, err := mime.ParseMediaType(s)
+	if err != nil {
+		return "", false
+	}
+	if charset, ok := params["charset"]; ok {
+		params["charset"] = strings.ToLower(charset)
+	}
+	if typ == "text/plain" && (len(params) == 0 || (len(params) == 1 && params["charset"] == "us-ascii")) {
+		return "", true
+	}
+
+	names := make([]string, 0, len(params))
+	for name, value := range params {
+		if value == "" || strings.ContainsAny(value, "()<>@,;:\\\"/[]?= \t") {
+			return "", false
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf strings.Builder
+	buf.WriteString(typ)
+	for _, name := range names {
+		buf.WriteString(";" + name + "=" + params[name])
+	}
+	return buf.String(), true
+}
+
+// normalizeURN lowercases the namespace identifier of a urn: URI and
+// uppercases the percent-encoded octets of the namespace specific string,
+// as per the lexical equivalence of RFC 8141. The namespace specific string
+// of the uuid namespace (RFC 4122) is lowercased. The r-, q- and
+// f-components are left untouched.
+func normalizeURN(u *url.URL) {
+	nid, nss, ok := strings.Cut(u.Opaque, ":")
+	if !ok || nid == "" || nss == "" {
+		return
+	}
+	nid = strings.ToLower(nid)
+	nss = upperEscapes(nss)
+	if nid == "uuid" {
+		nss = lowerKeepEscapes(nss)
+	}
+	u.Opaque = nid + ":" + nss
+}
+
+// Visual separators of the phone numbers of tel: URIs.
+var telSeparators = strings.NewReplacer("-", "", ".", "", "(", "", ")", "")
+
+// normalizeTel removes the visual separators from the number of a tel: URI
+// (RFC 3966), lowercases the parameter names and orders the parameters as
+// required for comparison: extension and ISDN subaddress first, then the
+// phone context, then the other parameters sorted by name.
+func normalizeTel(u *url.URL) {
+	if u.Opaque == "" {
+		return
+	}
+	parts := strings.Split(u.Opaque, ";")
+	number := telSeparators.Replace(parts[0])
+
+	params := make([]queryParam, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		name, value, hasValue := strings.Cut(p, "=")
+		name = lowerKeepEscapes(name)
+		switch name {
+		case "ext":
+			value = telSeparators.Replace(value)
+		case "phone-context":
+			if strings.HasPrefix(value, "+") {
+				value = telSeparators.Replace(value)
+			} else {
+				value = lowerKeepEscapes(value)
+			}
+		}
+		raw := name
+		if hasValue {
+			raw += "=" + value
+		}
+		params = append(params, queryParam{key: name, raw: raw})
+	}
+
+	rank := func(name string) int {
+		switch name {
+		case "ext":
+			return 0
+		case "isub":
+			return 1
+		case "phone-context":
+			return 2
+		}
+		return 3
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		ri, rj := rank(params[i].key), rank(params[j].key)
+		if ri != rj {
+			return ri < rj
+		}
+		return ri == 3 && params[i].key < params[j].key
+	})
+
+	var buf strings.Builder
+	buf.WriteString(number)
+	for _, p := range params {
+		buf.WriteString(";" + p.raw)
+	}
+	u.Opaque = buf.String()
+}
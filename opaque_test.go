@@ -0,0 +1,62 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestSchemeSpecificOpaque(t *testing.T) {
+	testcases := []struct {
+		nm  string
+		src string
+		res string
+	}{
+		// mailto:
+		{"MailtoDomain", "mailto:John.Doe@EXAMPLE.com", "mailto:John.Doe@example.com"},
+		{"MailtoScheme", "MAILTO:a@B.org", "mailto:a@b.org"},
+		{"MailtoSeveral", "mailto:a@X.org,b@Y.org", "mailto:a@x.org,b@y.org"},
+		{"MailtoEscapedAt", "mailto:a%40B.ORG", "mailto:a%40b.org"},
+		{"MailtoEscapedDomain", "mailto:a@%C3%A9.ORG", "mailto:a@%C3%A9.org"},
+		{"MailtoTo", "mailto:a@x.org?to=b@Y.org", "mailto:a@x.org,b@y.org"},
+		{"MailtoOnlyTo", "mailto:?to=b@Y.org", "mailto:b@y.org"},
+		{"MailtoDuplicates", "mailto:a@x.org?to=a@X.org%2Cb@y.org", "mailto:a@x.org,b@y.org"},
+		{"MailtoSortHeaders", "mailto:a@x.org?Subject=Hi%20there&body=Hello&CC=c@Z.org", "mailto:a@x.org?body=Hello&cc=c@z.org&subject=Hi%20there"},
+		{"MailtoMergeCc", "mailto:a@x.org?cc=c@x.org&bcc=d@x.org&cc=e@X.org,c@x.org", "mailto:a@x.org?bcc=d@x.org&cc=c@x.org,e@x.org"},
+		{"MailtoKeepOrder", "mailto:a@x.org?body=2&body=1", "mailto:a@x.org?body=2&body=1"},
+		{"MailtoHeaderWithoutValue", "mailto:a@x.org?subject", "mailto:a@x.org?subject"},
+
+		// data:
+		{"DataType", "data:Text/HTML,%3Cp%3E", "data:text/html,%3Cp%3E"},
+		{"DataParams", "data:text/plain;Foo=Bar;Charset=UTF-8,x", "data:text/plain;charset=utf-8;foo=Bar,x"},
+		{"DataDefault", "data:text/plain;charset=US-ASCII,x", "data:,x"},
+		{"DataDefaultType", "data:TEXT/PLAIN,x", "data:,x"},
+		{"DataCharsetOnly", "data:;charset=UTF-8,x", "data:text/plain;charset=utf-8,x"},
+		{"DataBase64", "data:Image/PNG;BASE64,iVBORw0KGgo=", "data:image/png;base64,iVBORw0KGgo="},
+		{"DataBase64Default", "data:;base64,SGk=", "data:;base64,SGk="},
+		{"DataInvalid", "data:text/,x", "data:text/,x"},
+		{"DataNoComma", "data:text/plain", "data:text/plain"},
+
+		// urn:
+		{"URNNID", "urn:ISBN:0451450523", "urn:isbn:0451450523"},
+		{"URNScheme", "URN:example:A%2fb", "urn:example:A%2Fb"},
+		{"URNNSSCase", "urn:example:Foo", "urn:example:Foo"},
+		{"URNUUID", "urn:UUID:6E8BC430-9C3A-11D9-9669-0800200C9A66", "urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66"},
+		{"URNComponents", "urn:Example:foo?+r?=q#f", "urn:example:foo?+r?=q#f"},
+		{"URNInvalid", "urn:example", "urn:example"},
+
+		// tel:
+		{"TelSeparators", "tel:+1-201-555-0123", "tel:+12015550123"},
+		{"TelParens", "tel:+1.(201).555.0123", "tel:+12015550123"},
+		{"TelExt", "tel:+1-201-555-0123;EXT=1-2", "tel:+12015550123;ext=12"},
+		{"TelParamOrder", "tel:7042;b=1;a=2;phone-context=EXAMPLE.com;isub=1", "tel:7042;isub=1;phone-context=example.com;a=2;b=1"},
+		{"TelGlobalContext", "tel:863-1234;phone-context=+1-914-555", "tel:8631234;phone-context=+1914555"},
+		{"TelFlag", "tel:+1-201-555-0123;isdn", "tel:+12015550123;isdn"},
+	}
+
+	for _, tc := range testcases {
+		if got, err := NormalizeURLString(tc.src, FlagSchemeSpecific); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package purell
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default ports of the schemes, used by FlagRemoveDefaultPort and
+// FlagAddDefaultPort. It starts with the special schemes of the WHATWG URL
+// Standard and some common schemes registered at the IANA.
+var (
+	defaultPortsMu sync.RWMutex
+	defaultPorts   = map[string]int{
+		"ftp":    21,
+		"gopher": 70,
+		"http":   80,
+		"https":  443,
+		"imap":   143,
+		"ldap":   389,
+		"ldaps":  636,
+		"nntp":   119,
+		"rtsp":   554,
+		"telnet": 23,
+		"ws":     80,
+		"wss":    443,
+	}
+)
+
+// RegisterDefaultPort sets the default port of the scheme, used by
+// FlagRemoveDefaultPort and FlagAddDefaultPort. It panics if the port is
+// not between 1 and 65535.
+func RegisterDefaultPort(scheme string, port int) {
+	if port < 1 || port > 65535 {
+		panic(fmt.Sprintf("purell: invalid default port %d for scheme %q", port, scheme))
+	}
+	defaultPortsMu.Lock()
+	defer defaultPortsMu.Unlock()
+	defaultPorts[strings.ToLower(scheme)] = port
+}
+
+// DefaultPort returns the default port of the scheme, and false if it has
+// none.
+func DefaultPort(scheme string) (int, bool) {
+	defaultPortsMu.RLock()
+	defer defaultPortsMu.RUnlock()
+	port, ok := defaultPorts[strings.ToLower(scheme)]
+	return port, ok
+}
+
+// WithDefaultPorts sets the default ports of the schemes used by
+// FlagRemoveDefaultPort and FlagAddDefaultPort, instead of those of
+// DefaultPort. It returns an error if a port is not between 1 and 65535.
+func WithDefaultPorts(ports map[string]int) Option {
+	return func(n *Normalizer) error {
+		m := make(map[string]int, len(ports))
+		for scheme, port := range ports {
+			if port < 1 || port > 65535 {
+				return fmt.Errorf("purell: invalid default port %d for scheme %q", port, scheme)
+			}
+			m[strings.ToLower(scheme)] = port
+		}
+		defaultPort := func(scheme string) (int, bool) {
+			port, ok := m[scheme]
+			return port, ok
+		}
+		n.funcs[FlagRemoveDefaultPort] = func(u *url.URL) {
+			removePort(u, defaultPort)
+		}
+		n.funcs[FlagAddDefaultPort] = func(u *url.URL) {
+			addPort(u, defaultPort)
+		}
+		return nil
+	}
+}
+
+func addDefaultPort(u *url.URL) {
+	addPort(u, DefaultPort)
+}
+
+func addPort(u *url.URL, defaultPort func(string) (int, bool)) {
+	if u.Host == "" || u.Port() != "" {
+		return
+	}
+	port, ok := defaultPort(strings.ToLower(u.Scheme))
+	if !ok {
+		return
+	}
+	// An empty port separator is replaced by the port
+	u.Host = strings.TrimSuffix(u.Host, ":") + ":" + strconv.Itoa(port)
+}
@@ -0,0 +1,103 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestDefaultPorts(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+		res  string
+	}{
+		{"RemoveHTTP", "http://host:80/", FlagRemoveDefaultPort, "http://host/"},
+		{"RemoveHTTPS", "https://host:443/", FlagRemoveDefaultPort, "https://host/"},
+		{"RemoveWS", "ws://host:80/chat", FlagRemoveDefaultPort, "ws://host/chat"},
+		{"RemoveWSS", "wss://host:443/chat", FlagRemoveDefaultPort, "wss://host/chat"},
+		{"RemoveFTP", "ftp://user@host:21/file", FlagRemoveDefaultPort, "ftp://user@host/file"},
+		{"RemoveGopher", "gopher://host:70/1", FlagRemoveDefaultPort, "gopher://host/1"},
+		{"RemoveUppercaseScheme", "WSS://host:443/", FlagRemoveDefaultPort, "wss://host/"},
+		{"KeepOtherPort", "ws://host:443/", FlagRemoveDefaultPort, "ws://host:443/"},
+		{"KeepUnknownScheme", "foo://host:80/", FlagRemoveDefaultPort, "foo://host:80/"},
+		{"RemoveIPv6", "https://[::1]:443/", FlagRemoveDefaultPort, "https://[::1]/"},
+		{"AddHTTP", "http://host/", FlagAddDefaultPort, "http://host:80/"},
+		{"AddWSS", "wss://host/chat", FlagAddDefaultPort, "wss://host:443/chat"},
+		{"AddIPv6", "https://[::1]/", FlagAddDefaultPort, "https://[::1]:443/"},
+		{"AddEmptyPort", "http://host:/", FlagAddDefaultPort, "http://host:80/"},
+		{"AddKeepsPort", "http://host:8080/", FlagAddDefaultPort, "http://host:8080/"},
+		{"AddUnknownScheme", "foo://host/", FlagAddDefaultPort, "foo://host/"},
+		{"AddNoHost", "mailto:user@host", FlagAddDefaultPort, "mailto:user@host"},
+		{"AddAfterForceHTTP", "https://host/", FlagAddDefaultPort | FlagForceHTTP, "http://host:80/"},
+		{"AddAfterRemove", "https://host:443/", FlagAddDefaultPort | FlagRemoveDefaultPort, "https://host:443/"},
+	}
+
+	for _, tc := range testcases {
+		if got, err := NormalizeURLString(tc.src, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestRegisterDefaultPort(t *testing.T) {
+	if _, ok := DefaultPort("x-purell-test"); ok {
+		t.Fatalf("FAIL expected no default port")
+	}
+	RegisterDefaultPort("X-Purell-Test", 8443)
+	defer func() {
+		defaultPortsMu.Lock()
+		delete(defaultPorts, "x-purell-test")
+		defaultPortsMu.Unlock()
+	}()
+
+	if port, ok := DefaultPort("x-purell-test"); !ok || port != 8443 {
+		t.Errorf("FAIL expected 8443, got %d", port)
+	}
+	if got := MustNormalizeURLString("x-purell-test://host:8443/", FlagRemoveDefaultPort); got != "x-purell-test://host/" {
+		t.Errorf("FAIL expected '%s', got '%s'", "x-purell-test://host/", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("FAIL expected panic")
+		}
+	}()
+	RegisterDefaultPort("x-purell-test", 0)
+}
+
+func TestWithDefaultPorts(t *testing.T) {
+	n := MustNewNormalizer(FlagRemoveDefaultPort, WithDefaultPorts(map[string]int{"Internal": 9000}))
+	if got := n.MustNormalizeString("internal://host:9000/"); got != "internal://host/" {
+		t.Errorf("FAIL expected '%s', got '%s'", "internal://host/", got)
+	}
+	if got := n.MustNormalizeString("http://host:80/"); got != "http://host:80/" {
+		t.Errorf("FAIL expected '%s', got '%s'", "http://host:80/", got)
+	}
+
+	n = MustNewNormalizer(FlagAddDefaultPort, WithDefaultPorts(map[string]int{"internal": 9000}))
+	if got := n.MustNormalizeString("internal://host/"); got != "internal://host:9000/" {
+		t.Errorf("FAIL expected '%s', got '%s'", "internal://host:9000/", got)
+	}
+
+	if _, err := NewNormalizer(FlagsSafe, WithDefaultPorts(map[string]int{"internal": 70000})); err == nil {
+		t.Errorf("FAIL expected error")
+	}
+}
+
+func TestWithDefaultPortsPreviousBehavior(t *testing.T) {
+	n := MustNewNormalizer(FlagsSafe, WithDefaultPorts(map[string]int{"http": 80, "https": 443}))
+	for src, res := range map[string]string{
+		"http://host:80/":   "http://host/",
+		"https://host:443/": "https://host/",
+		"ftp://host:21/":    "ftp://host:21/",
+		"ws://host:80/":     "ws://host:80/",
+		"gopher://host:70/": "gopher://host:70/",
+		"ldap://host:389/x": "ldap://host:389/x",
+	} {
+		if got := n.MustNormalizeString(src); got != res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", src, res, got)
+		}
+	}
+}
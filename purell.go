@@ -14,8 +14,6 @@ import (
 	"strings"
 
 	"golang.org/x/net/idna"
-	"golang.org/x/text/unicode/norm"
-	"golang.org/x/text/width"
 )
 
 // A set of normalization flags determines how a URL will
@@ -48,12 +46,27 @@ const (
 
 	// Normalizations not in the wikipedia article, required to cover tests cases
 	// submitted by jehiah
+	//
+	// Deprecated: the three IP host flags only decode some forms of IPv4
+	// addresses, use FlagDecodeIPv4Host instead.
 	FlagDecodeDWORDHost           // http://1113982867 -> http://66.102.7.147
 	FlagDecodeOctalHost           // http://0102.0146.07.0223 -> http://66.102.7.147
 	FlagDecodeHexHost             // http://0x42660793 -> http://66.102.7.147
 	FlagRemoveUnnecessaryHostDots // http://.host../path -> http://host/path
 	FlagRemoveEmptyPortSeparator  // http://host:/path -> http://host/path
 
+	// Normalizations added later on, not part of the convenience sets so that
+	// their results stay stable
+	FlagRemoveTrackingParams // http://host/path?utm_source=x&a=1 -> http://host/path?a=1
+	FlagCanonicalizeIPv6Host // http://[2001:DB8:0:0:0:0:0:1]/ -> http://[2001:db8::1]/
+	FlagDecodeIPv4Host       // http://0x7f.1 -> http://127.0.0.1
+	FlagAddDefaultPort       // http://host/path -> http://host:80/path
+	FlagSchemeSpecific       // mailto:Joe@EXAMPLE.com -> mailto:Joe@example.com
+	FlagRemoveSessionIDs     // http://host/path;jsessionid=ABC?PHPSESSID=DEF&a=1 -> http://host/path?a=1
+	FlagRemoveMatrixParams   // http://host/a;v=1/b;c=2 -> http://host/a/b
+	FlagSortMatrixParams     // http://host/a;v=1;b=2/c -> http://host/a;b=2;v=1/c
+	FlagSortRawQuery         // http://host/path?c=3&b=2&a=%20&b=1 -> http://host/path?a=%20&b=2&b=1&c=3
+
 	// Convenience set of safe normalizations
 	FlagsSafe NormalizationFlags = FlagLowercaseHost | FlagLowercaseScheme | FlagUppercaseEscapes | FlagDecodeUnnecessaryEscapes | FlagEncodeNecessaryEscapes | FlagRemoveDefaultPort | FlagRemoveEmptyQuerySeparator
 
@@ -73,11 +86,6 @@ const (
 	FlagsAllNonGreedy = FlagsUnsafeNonGreedy | FlagDecodeDWORDHost | FlagDecodeOctalHost | FlagDecodeHexHost | FlagRemoveUnnecessaryHostDots | FlagRemoveEmptyPortSeparator
 )
 
-const (
-	defaultHttpPort  = ":80"
-	defaultHttpsPort = ":443"
-)
-
 // Regular expressions used by the normalizations
 var rxPort = regexp.MustCompile(`(:\d+)/?$`)
 var rxDirIndex = regexp.MustCompile(`(^|/)((?:default|index)\.\w{1,4})$`)
@@ -97,7 +105,11 @@ var rxEmptyPort = regexp.MustCompile(`:+$`)
 var flagsOrder = []NormalizationFlags{
 	FlagLowercaseScheme,
 	FlagLowercaseHost,
+	FlagSchemeSpecific,
 	FlagRemoveDefaultPort,
+	FlagRemoveSessionIDs, // Must be before remove directory index and sort query
+	FlagRemoveMatrixParams,
+	FlagSortMatrixParams,
 	FlagRemoveDirectoryIndex,
 	FlagRemoveDotSegments,
 	FlagRemoveFragment,
@@ -105,12 +117,17 @@ var flagsOrder = []NormalizationFlags{
 	FlagRemoveDuplicateSlashes,
 	FlagRemoveWWW,
 	FlagAddWWW,
+	FlagRemoveTrackingParams, // Must be before the sort query flags
 	FlagSortQuery,
+	FlagSortRawQuery,
 	FlagDecodeDWORDHost,
 	FlagDecodeOctalHost,
 	FlagDecodeHexHost,
+	FlagDecodeIPv4Host,
+	FlagCanonicalizeIPv6Host,
 	FlagRemoveUnnecessaryHostDots,
 	FlagRemoveEmptyPortSeparator,
+	FlagAddDefaultPort,      // Must be after force HTTP and the host normalizations
 	FlagRemoveTrailingSlash, // These two (add/remove trailing slash) must be last
 	FlagAddTrailingSlash,
 }
@@ -127,16 +144,61 @@ var flags = map[NormalizationFlags]func(*url.URL){
 	FlagRemoveDuplicateSlashes:    removeDuplicateSlashes,
 	FlagRemoveWWW:                 removeWWW,
 	FlagAddWWW:                    addWWW,
+	FlagRemoveTrackingParams:      removeTrackingParams,
 	FlagSortQuery:                 sortQuery,
 	FlagDecodeDWORDHost:           decodeDWORDHost,
 	FlagDecodeOctalHost:           decodeOctalHost,
 	FlagDecodeHexHost:             decodeHexHost,
+	FlagDecodeIPv4Host:            decodeIPv4Host,
+	FlagCanonicalizeIPv6Host:      canonicalizeIPv6Host,
 	FlagRemoveUnnecessaryHostDots: removeUnncessaryHostDots,
 	FlagRemoveEmptyPortSeparator:  removeEmptyPortSeparator,
+	FlagAddDefaultPort:            addDefaultPort,
+	FlagSchemeSpecific:            normalizeSchemeSpecific,
+	FlagRemoveSessionIDs:          removeSessionIDs,
+	FlagRemoveMatrixParams:        removeMatrixParams,
+	FlagSortMatrixParams:          sortMatrixParams,
+	FlagSortRawQuery:              sortRawQuery,
 	FlagRemoveTrailingSlash:       removeTrailingSlash,
 	FlagAddTrailingSlash:          addTrailingSlash,
 }
 
+// Names of the flags, used to identify the corresponding built-in steps
+// of a Normalizer.
+var flagNames = map[NormalizationFlags]string{
+	FlagLowercaseScheme:           "lowercase-scheme",
+	FlagLowercaseHost:             "lowercase-host",
+	FlagUppercaseEscapes:          "uppercase-escapes",
+	FlagDecodeUnnecessaryEscapes:  "decode-unnecessary-escapes",
+	FlagEncodeNecessaryEscapes:    "encode-necessary-escapes",
+	FlagRemoveDefaultPort:         "remove-default-port",
+	FlagRemoveEmptyQuerySeparator: "remove-empty-query-separator",
+	FlagRemoveTrailingSlash:       "remove-trailing-slash",
+	FlagAddTrailingSlash:          "add-trailing-slash",
+	FlagRemoveDotSegments:         "remove-dot-segments",
+	FlagRemoveDirectoryIndex:      "remove-directory-index",
+	FlagRemoveFragment:            "remove-fragment",
+	FlagForceHTTP:                 "force-http",
+	FlagRemoveDuplicateSlashes:    "remove-duplicate-slashes",
+	FlagRemoveWWW:                 "remove-www",
+	FlagAddWWW:                    "add-www",
+	FlagSortQuery:                 "sort-query",
+	FlagDecodeDWORDHost:           "decode-dword-host",
+	FlagDecodeOctalHost:           "decode-octal-host",
+	FlagDecodeHexHost:             "decode-hex-host",
+	FlagRemoveUnnecessaryHostDots: "remove-unnecessary-host-dots",
+	FlagRemoveEmptyPortSeparator:  "remove-empty-port-separator",
+	FlagRemoveTrackingParams:      "remove-tracking-params",
+	FlagCanonicalizeIPv6Host:      "canonicalize-ipv6-host",
+	FlagDecodeIPv4Host:            "decode-ipv4-host",
+	FlagAddDefaultPort:            "add-default-port",
+	FlagSchemeSpecific:            "scheme-specific",
+	FlagRemoveSessionIDs:          "remove-session-ids",
+	FlagRemoveMatrixParams:        "remove-matrix-params",
+	FlagSortMatrixParams:          "sort-matrix-params",
+	FlagSortRawQuery:              "sort-raw-query",
+}
+
 // MustNormalizeURLString returns the normalized string, and panics if an error occurs.
 // It takes an URL string as input, as well as the normalization flags.
 func MustNormalizeURLString(u string, f NormalizationFlags) string {
@@ -150,37 +212,81 @@ func MustNormalizeURLString(u string, f NormalizationFlags) string {
 // NormalizeURLString returns the normalized string, or an error if it can't be parsed into an URL object.
 // It takes an URL string as input, as well as the normalization flags.
 func NormalizeURLString(u string, f NormalizationFlags) (string, error) {
-	parsed, err := url.Parse(u)
+	parsed, err := parseURL(u, f, idna.Punycode)
 	if err != nil {
 		return "", err
 	}
+	return NormalizeURL(parsed, f), nil
+}
+
+// parseURL parses the URL string and converts its host to its ASCII form,
+// as required before applying the normalization flags.
+func parseURL(u string, f NormalizationFlags, p *idna.Profile) (*url.URL, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
 
 	if f&FlagLowercaseHost == FlagLowercaseHost {
 		parsed.Host = strings.ToLower(parsed.Host)
 	}
 
-	// The idna package doesn't fully conform to RFC 5895
-	// (https://tools.ietf.org/html/rfc5895), so we do it here.
-	// Taken from Go 1.8 cycle source, courtesy of bradfitz.
-	// TODO: Remove when (if?) idna package conforms to RFC 5895.
-	parsed.Host = width.Fold.String(parsed.Host)
-	parsed.Host = norm.NFC.String(parsed.Host)
-	if parsed.Host, err = idna.ToASCII(parsed.Host); err != nil {
-		return "", err
+	if parsed.Host, err = hostToASCII(p, parsed.Host); err != nil {
+		return nil, err
 	}
 
-	return NormalizeURL(parsed, f), nil
+	if f&FlagDecodeIPv4Host == FlagDecodeIPv4Host {
+		if _, err = ipv4Host(parsed.Host); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
 }
 
 // NormalizeURL returns the normalized string.
 // It takes a parsed URL object as input, as well as the normalization flags.
 func NormalizeURL(u *url.URL, f NormalizationFlags) string {
+	applyFlags(u, f)
+	return escapeURLSemicolons(u, f&matrixFlags != 0)
+}
+
+// NormalizedURL returns a normalized copy of the URL object, leaving it
+// untouched. The copy is parsed from the normalized string, so that its
+// RawPath, RawQuery and RawFragment fields match the string returned by
+// NormalizeURL.
+func NormalizedURL(u *url.URL, f NormalizationFlags) *url.URL {
+	clone := cloneURL(u)
+	return reparseURL(NormalizeURL(clone, f), clone)
+}
+
+// cloneURL returns a copy of the URL object that can be modified without
+// affecting the original.
+func cloneURL(u *url.URL) *url.URL {
+	clone := *u
+	if u.User != nil {
+		user := *u.User
+		clone.User = &user
+	}
+	return &clone
+}
+
+// reparseURL parses the normalized string of the URL object. The object is
+// returned as is in the unlikely event that the string can't be parsed.
+func reparseURL(normalized string, u *url.URL) *url.URL {
+	parsed, err := url.Parse(normalized)
+	if err != nil {
+		return u
+	}
+	return parsed
+}
+
+// applyFlags applies the normalizations of the flags to the URL object.
+func applyFlags(u *url.URL, f NormalizationFlags) {
 	for _, k := range flagsOrder {
 		if f&k == k {
 			flags[k](u)
 		}
 	}
-	return escapeURL(u)
 }
 
 func lowercaseScheme(u *url.URL) {
@@ -196,10 +302,17 @@ func lowercaseHost(u *url.URL) {
 }
 
 func removeDefaultPort(u *url.URL) {
+	removePort(u, DefaultPort)
+}
+
+func removePort(u *url.URL, defaultPort func(string) (int, bool)) {
 	if len(u.Host) > 0 {
-		scheme := strings.ToLower(u.Scheme)
+		port, ok := defaultPort(strings.ToLower(u.Scheme))
+		if !ok {
+			return
+		}
 		u.Host = rxPort.ReplaceAllStringFunc(u.Host, func(val string) string {
-			if (scheme == "http" && val == defaultHttpPort) || (scheme == "https" && val == defaultHttpsPort) {
+			if val == ":"+strconv.Itoa(port) {
 				return ""
 			}
 			return val
@@ -787,3 +787,23 @@ func TestEncodeNecessaryEscapesAll(t *testing.T) {
 		t.Errorf("EncodeNecessaryEscapesAll:\nwant\n%s\ngot\n%s", want, s)
 	}
 }
+
+func TestNormalizedURL(t *testing.T) {
+	for _, tc := range cases {
+		u, err := url.Parse(tc.src)
+		if err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+			continue
+		}
+		orig := u.String()
+		want := NormalizeURL(cloneURL(u), tc.flgs)
+
+		got := NormalizedURL(u, tc.flgs)
+		if s := got.String(); s != want {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, want, s)
+		}
+		if s := u.String(); s != orig {
+			t.Errorf("%s - FAIL expected the URL object to be untouched '%s', got '%s'", tc.nm, orig, s)
+		}
+	}
+}
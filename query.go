@@ -0,0 +1,279 @@
+package purell
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Well-known tracking query parameters, removed by FlagRemoveTrackingParams.
+var defaultTrackingParams = []string{
+	"utm_*",
+	"_ga",
+	"_gl",
+	"_hsenc",
+	"_hsmi",
+	"dclid",
+	"fbclid",
+	"gbraid",
+	"gclid",
+	"gclsrc",
+	"hsa_*",
+	"igshid",
+	"mc_cid",
+	"mc_eid",
+	"mkt_tok",
+	"msclkid",
+	"oly_anon_id",
+	"oly_enc_id",
+	"rb_clickid",
+	"s_cid",
+	"ttclid",
+	"twclid",
+	"vero_id",
+	"wbraid",
+	"yclid",
+}
+
+var trackingParams = mustParamMatcher(defaultTrackingParams)
+
+// DefaultTrackingParams returns the names and patterns of the query
+// parameters removed by FlagRemoveTrackingParams, unless a Normalizer is
+// configured otherwise using WithTrackingParams.
+func DefaultTrackingParams() []string {
+	return append([]string(nil), defaultTrackingParams...)
+}
+
+// WithTrackingParams sets the names and patterns of the query parameters
+// removed by FlagRemoveTrackingParams. Patterns use the syntax of path.Match,
+// e.g. "utm_*". Parameter names are matched case-sensitively, after
+// unescaping.
+func WithTrackingParams(patterns ...string) Option {
+	return func(n *Normalizer) error {
+		m, err := newParamMatcher(patterns)
+		if err != nil {
+			return err
+		}
+		n.funcs[FlagRemoveTrackingParams] = func(u *url.URL) {
+			removeQueryParams(u, m.match)
+		}
+		return nil
+	}
+}
+
+// paramMatcher matches query parameter names against exact names and
+// path.Match patterns.
+type paramMatcher struct {
+	names    map[string]bool
+	patterns []string
+}
+
+func newParamMatcher(patterns []string) (*paramMatcher, error) {
+	m := &paramMatcher{names: make(map[string]bool)}
+	for _, p := range patterns {
+		if !strings.ContainsAny(p, `*?[\`) {
+			m.names[p] = true
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("purell: invalid parameter pattern %q: %w", p, err)
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+func mustParamMatcher(patterns []string) *paramMatcher {
+	m, err := newParamMatcher(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m *paramMatcher) match(name string) bool {
+	if m.names[name] {
+		return true
+	}
+	for _, p := range m.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// queryParam is a key=value pair of a raw query string. The key is
+// unescaped, while raw holds the pair exactly as found in the query.
+type queryParam struct {
+	key string
+	raw string
+}
+
+// parseQuery splits the raw query string into its pairs, skipping the
+// empty ones.
+func parseQuery(rawQuery string) []queryParam {
+	var params []queryParam
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		key := raw
+		if i := strings.IndexByte(key, '='); i >= 0 {
+			key = key[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		params = append(params, queryParam{key: key, raw: raw})
+	}
+	return params
+}
+
+// value returns the unescaped value of the pair.
+func (p queryParam) value() string {
+	i := strings.IndexByte(p.raw, '=')
+	if i < 0 {
+		return ""
+	}
+	v := p.raw[i+1:]
+	if uv, err := url.QueryUnescape(v); err == nil {
+		v = uv
+	}
+	return v
+}
+
+// formatQuery joins the pairs into a raw query string.
+func formatQuery(params []queryParam) string {
+	raws := make([]string, len(params))
+	for i, p := range params {
+		raws[i] = p.raw
+	}
+	return strings.Join(raws, "&")
+}
+
+// removeQueryParams removes the query parameters whose key matches, keeping
+// the other pairs untouched. The query separator is dropped if no parameter
+// remains.
+func removeQueryParams(u *url.URL, match func(string) bool) {
+	removeQueryPairs(u, func(p queryParam) bool {
+		return match(p.key)
+	})
+}
+
+// removeQueryPairs is like removeQueryParams, but matches the whole pairs.
+func removeQueryPairs(u *url.URL, match func(queryParam) bool) {
+	if u.RawQuery == "" {
+		return
+	}
+
+	params := parseQuery(u.RawQuery)
+	kept := params[:0]
+	for _, p := range params {
+		if !match(p) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) < len(params) {
+		u.RawQuery = formatQuery(kept)
+		if u.RawQuery == "" {
+			u.ForceQuery = false
+		}
+	}
+}
+
+func removeTrackingParams(u *url.URL) {
+	removeQueryParams(u, trackingParams.match)
+}
+
+// sortRawQuery sorts the query parameters by key, without changing their
+// encoding nor the relative order of the parameters of the same key. Empty
+// parameters are removed.
+func sortRawQuery(u *url.URL) {
+	if u.RawQuery == "" {
+		return
+	}
+
+	params := parseQuery(u.RawQuery)
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].key < params[j].key
+	})
+	u.RawQuery = formatQuery(params)
+}
+
+// A QueryRule keeps or drops query parameters of the URLs of some hosts.
+type QueryRule struct {
+	// Host selects the hosts the rule applies to: "www.example.com" matches
+	// that host only, "*.example.com" matches any subdomain of example.com
+	// (but not example.com itself) and "site:example.com" matches every host
+	// whose registrable domain is example.com.
+	Host string
+
+	// Keep lists the names and patterns of the parameters to keep, all other
+	// parameters are removed. If empty, all parameters are kept.
+	Keep []string
+
+	// Drop lists the names and patterns of the parameters to remove.
+	Drop []string
+}
+
+// WithQueryRules adds a step named "query-rules" that runs right before
+// the step of FlagRemoveWWW and applies, to each URL, the first rule that
+// matches its host. Rules thus match the host before FlagRemoveWWW and
+// FlagAddWWW rewrite it, as for WithDirectoryIndex. The option may be given
+// several times, the rules are then tried in the order they were given.
+// Names and patterns follow the syntax of WithTrackingParams.
+func WithQueryRules(rules ...QueryRule) Option {
+	return func(n *Normalizer) error {
+		compiled := make([]queryRule, len(rules))
+		for i, r := range rules {
+			var err error
+			if compiled[i].host, err = newHostPattern(r.Host); err != nil {
+				return err
+			}
+			if len(r.Keep) > 0 {
+				if compiled[i].keep, err = newParamMatcher(r.Keep); err != nil {
+					return err
+				}
+			}
+			if compiled[i].drop, err = newParamMatcher(r.Drop); err != nil {
+				return err
+			}
+		}
+		if n.queryRules == nil {
+			// the step is added once and applies the rules of every call
+			n.queryRules = make([]queryRule, 0, len(compiled))
+			n.insertBefore(FlagRemoveWWW, step{name: "query-rules", fn: func(u *url.URL) {
+				applyQueryRules(u, n.queryRules)
+			}})
+		}
+		n.queryRules = append(n.queryRules, compiled...)
+		return nil
+	}
+}
+
+// queryRule is the compiled form of a QueryRule. The keep matcher is nil if
+// all parameters are kept.
+type queryRule struct {
+	host hostPattern
+	keep *paramMatcher
+	drop *paramMatcher
+}
+
+func applyQueryRules(u *url.URL, rules []queryRule) {
+	if u.RawQuery == "" {
+		return
+	}
+
+	host := hostname(u)
+	for _, r := range rules {
+		if r.host.match(host) {
+			removeQueryParams(u, func(key string) bool {
+				return (r.keep != nil && !r.keep.match(key)) || r.drop.match(key)
+			})
+			return
+		}
+	}
+}
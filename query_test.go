@@ -0,0 +1,285 @@
+package purell
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRemoveTrackingParams(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+		res  string
+	}{
+		{
+			"Prefix",
+			"http://host/path?utm_source=x&a=1&utm_medium=y",
+			FlagRemoveTrackingParams,
+			"http://host/path?a=1",
+		},
+		{
+			"Exact",
+			"http://host/path?fbclid=abc&b=2&gclid=def&a=1",
+			FlagRemoveTrackingParams,
+			"http://host/path?b=2&a=1",
+		},
+		{
+			"AllRemoved",
+			"http://host/path?utm_source=x&gclid=1#frag",
+			FlagRemoveTrackingParams,
+			"http://host/path#frag",
+		},
+		{
+			"EscapedKey",
+			"http://host/path?utm%5Fsource=x&a=1",
+			FlagRemoveTrackingParams,
+			"http://host/path?a=1",
+		},
+		{
+			"CaseSensitive",
+			"http://host/path?UTM_SOURCE=x&Gclid=1",
+			FlagRemoveTrackingParams,
+			"http://host/path?UTM_SOURCE=x&Gclid=1",
+		},
+		{
+			"KeepsEncoding",
+			"http://host/path?q=a%20b+c&utm_campaign=x&z",
+			FlagRemoveTrackingParams,
+			"http://host/path?q=a%20b+c&z",
+		},
+		{
+			"NothingRemoved",
+			"http://host/path?a=1&&b=2",
+			FlagRemoveTrackingParams,
+			"http://host/path?a=1&&b=2",
+		},
+		{
+			"BeforeSort",
+			"http://host/path?c=3&utm_source=x&a=1",
+			FlagRemoveTrackingParams | FlagSortQuery,
+			"http://host/path?a=1&c=3",
+		},
+		{
+			"NotSet",
+			"http://host/path?utm_source=x",
+			FlagsAllGreedy,
+			"http://host/path?utm_source=x",
+		},
+	}
+
+	for _, tc := range testcases {
+		if got, err := NormalizeURLString(tc.src, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestWithTrackingParams(t *testing.T) {
+	n, err := NewNormalizer(FlagRemoveTrackingParams, WithTrackingParams(append(DefaultTrackingParams(), "ref", "src_?")...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "http://host/?a=1&src_ab=3"
+	if got := n.MustNormalizeString("http://host/?ref=x&a=1&src_a=2&utm_id=1&src_ab=3"); got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+
+	if _, err := NewNormalizer(FlagRemoveTrackingParams, WithTrackingParams("utm_[")); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestDefaultTrackingParamsCopy(t *testing.T) {
+	params := DefaultTrackingParams()
+	params[0] = "a"
+	if got := MustNormalizeURLString("http://host/?a=1&utm_source=x", FlagRemoveTrackingParams); got != "http://host/?a=1" {
+		t.Errorf("default tracking parameters were modified, got '%s'", got)
+	}
+}
+
+func TestWithQueryRules(t *testing.T) {
+	n, err := NewNormalizer(FlagsSafe|FlagSortQuery, WithQueryRules(
+		QueryRule{Host: "shop.example.com", Keep: []string{"id", "page"}},
+		QueryRule{Host: "*.example.com", Drop: []string{"sessionid"}},
+		QueryRule{Host: "site:example.org", Keep: []string{"q"}, Drop: []string{"q"}},
+		QueryRule{Host: "site:example.net", Drop: []string{"ref", "ref_*"}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		src string
+		res string
+	}{
+		{"http://shop.example.com/?page=2&ref=x&id=5", "http://shop.example.com/?id=5&page=2"},
+		{"http://SHOP.example.com./?ref=x", "http://shop.example.com./"},
+		{"http://www.example.com/?sessionid=1&ref=x&id=5", "http://www.example.com/?id=5&ref=x"},
+		{"http://example.com/?sessionid=1", "http://example.com/?sessionid=1"},
+		{"http://a.b.example.org:8080/?q=1&b=2", "http://a.b.example.org:8080/"},
+		{"http://example.net/?ref=1&ref_src=2&b=2", "http://example.net/?b=2"},
+		{"http://other.com/?sessionid=1&ref=x", "http://other.com/?ref=x&sessionid=1"},
+	}
+	for _, tc := range testcases {
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.src, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.src, tc.res, got)
+		}
+	}
+}
+
+func TestWithQueryRulesWWW(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+		res  string
+	}{
+		{
+			"RemoveWWW",
+			"http://www.example.com/?ref=1&id=2",
+			FlagRemoveWWW,
+			"http://example.com/?id=2",
+		},
+		{
+			"RemoveWWWOtherHost",
+			"http://example.com/?ref=1&id=2",
+			FlagRemoveWWW,
+			"http://example.com/?ref=1&id=2",
+		},
+		{
+			"AddWWW",
+			"http://example.com/?ref=1&id=2",
+			FlagAddWWW,
+			"http://www.example.com/?ref=1&id=2",
+		},
+	}
+
+	for _, tc := range testcases {
+		n := MustNewNormalizer(tc.flgs, WithQueryRules(QueryRule{Host: "www.example.com", Drop: []string{"ref"}}))
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestWithQueryRulesRepeated(t *testing.T) {
+	n, err := NewNormalizer(FlagsSafe,
+		WithQueryRules(),
+		WithQueryRules(QueryRule{Host: "example.com", Drop: []string{"a"}}),
+		WithQueryRules(QueryRule{Host: "example.com", Drop: []string{"b"}}, QueryRule{Host: "example.org", Drop: []string{"b"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		src string
+		res string
+	}{
+		{"http://example.com/?a=1&b=2", "http://example.com/?b=2"},
+		{"http://example.org/?a=1&b=2", "http://example.org/?a=1"},
+	}
+	for _, tc := range testcases {
+		if got, err := n.NormalizeString(tc.src); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.src, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.src, tc.res, got)
+		}
+	}
+}
+
+func TestWithQueryRulesInvalid(t *testing.T) {
+	for _, r := range []QueryRule{
+		{Host: "a*b.com"},
+		{Host: "example.com", Keep: []string{"["}},
+		{Host: "example.com", Drop: []string{"["}},
+	} {
+		if _, err := NewNormalizer(FlagsSafe, WithQueryRules(r)); err == nil {
+			t.Errorf("%+v - FAIL expected error", r)
+		}
+	}
+}
+
+func TestWithQueryRulesOrdering(t *testing.T) {
+	var query string
+	n := MustNewNormalizer(FlagSortQuery,
+		WithQueryRules(QueryRule{Host: "example.com", Drop: []string{"b"}}),
+		WithStep(Step{
+			Name:   "capture",
+			Func:   func(u *url.URL) { query = u.RawQuery },
+			After:  []string{"query-rules"},
+			Before: []string{"sort-query"},
+		}),
+	)
+	n.MustNormalizeString("http://example.com/?c=3&b=2&a=1")
+	if query != "c=3&a=1" {
+		t.Errorf("expected query rules to run before sort, got '%s'", query)
+	}
+}
+
+func TestSortRawQuery(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+		res  string
+	}{
+		{
+			"Sort",
+			"http://host/path?c=3&b=2&a=1",
+			FlagSortRawQuery,
+			"http://host/path?a=1&b=2&c=3",
+		},
+		{
+			"KeepsValueOrder",
+			"http://host/path?c=3&b=2&a=%20&b=1",
+			FlagSortRawQuery,
+			"http://host/path?a=%20&b=2&b=1&c=3",
+		},
+		{
+			"KeepsEncoding",
+			"http://host/path?z=a+b&y=a%20b&x=%2f&w=%C3%A9&v",
+			FlagSortRawQuery,
+			"http://host/path?v&w=%C3%A9&x=%2f&y=a%20b&z=a+b",
+		},
+		{
+			"EscapedKeys",
+			"http://host/path?%62=2&a=1&c=3",
+			FlagSortRawQuery,
+			"http://host/path?a=1&%62=2&c=3",
+		},
+		{
+			"EmptyPairs",
+			"http://host/path?b=2&&a=1&",
+			FlagSortRawQuery,
+			"http://host/path?a=1&b=2",
+		},
+		{
+			"AfterTrackingParams",
+			"http://host/path?c=3&utm_source=x&a=1",
+			FlagRemoveTrackingParams | FlagSortRawQuery,
+			"http://host/path?a=1&c=3",
+		},
+		{
+			"NotSet",
+			"http://host/path?c=3&b=2",
+			FlagsAllGreedy &^ FlagSortQuery,
+			"http://host/path?c=3&b=2",
+		},
+	}
+
+	for _, tc := range testcases {
+		if got, err := NormalizeURLString(tc.src, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package purell
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Normalization functions of the schemes, applied by FlagSchemeSpecific.
+var (
+	schemeNormalizersMu sync.RWMutex
+	schemeNormalizers   = map[string]func(*url.URL){
+		"data":   normalizeData,
+		"file":   normalizeFile,
+		"mailto": normalizeMailto,
+		"tel":    normalizeTel,
+		"urn":    normalizeURN,
+	}
+)
+
+// RegisterSchemeNormalizer sets the normalization function of the URLs of
+// the scheme, applied by FlagSchemeSpecific. It replaces the built-in one,
+// if any, and a nil function removes it.
+func RegisterSchemeNormalizer(scheme string, fn func(*url.URL)) {
+	schemeNormalizersMu.Lock()
+	defer schemeNormalizersMu.Unlock()
+	if fn == nil {
+		delete(schemeNormalizers, strings.ToLower(scheme))
+		return
+	}
+	schemeNormalizers[strings.ToLower(scheme)] = fn
+}
+
+// WithSchemeNormalizer sets the normalization function of the URLs of the
+// scheme applied by FlagSchemeSpecific, for the Normalizer only. A nil
+// function disables the registered one, if any.
+func WithSchemeNormalizer(scheme string, fn func(*url.URL)) Option {
+	return func(n *Normalizer) error {
+		scheme = strings.ToLower(scheme)
+		next, ok := n.funcs[FlagSchemeSpecific]
+		if !ok {
+			next = normalizeSchemeSpecific
+		}
+		n.funcs[FlagSchemeSpecific] = func(u *url.URL) {
+			if strings.ToLower(u.Scheme) != scheme {
+				next(u)
+			} else if fn != nil {
+				fn(u)
+			}
+		}
+		return nil
+	}
+}
+
+func normalizeSchemeSpecific(u *url.URL) {
+	schemeNormalizersMu.RLock()
+	fn := schemeNormalizers[strings.ToLower(u.Scheme)]
+	schemeNormalizersMu.RUnlock()
+	if fn != nil {
+		fn(u)
+	}
+}
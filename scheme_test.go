@@ -0,0 +1,57 @@
+package purell
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func upperOpaque(u *url.URL) {
+	u.Opaque = strings.ToUpper(u.Opaque)
+}
+
+func TestRegisterSchemeNormalizer(t *testing.T) {
+	const src = "x-purell-test:abc"
+	if got := MustNormalizeURLString(src, FlagSchemeSpecific); got != src {
+		t.Fatalf("FAIL expected '%s', got '%s'", src, got)
+	}
+
+	RegisterSchemeNormalizer("X-Purell-Test", upperOpaque)
+	if got, want := MustNormalizeURLString(src, FlagSchemeSpecific), "x-purell-test:ABC"; got != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, got)
+	}
+	if got := MustNormalizeURLString(src, FlagsAllGreedy); got != src {
+		t.Errorf("FAIL expected '%s' without the flag, got '%s'", src, got)
+	}
+
+	RegisterSchemeNormalizer("x-purell-test", nil)
+	if got := MustNormalizeURLString(src, FlagSchemeSpecific); got != src {
+		t.Errorf("FAIL expected '%s', got '%s'", src, got)
+	}
+}
+
+func TestWithSchemeNormalizer(t *testing.T) {
+	n := MustNewNormalizer(FlagSchemeSpecific,
+		WithSchemeNormalizer("x-purell-test", upperOpaque),
+		WithSchemeNormalizer("URN", nil),
+	)
+
+	testcases := []struct {
+		nm  string
+		src string
+		res string
+	}{
+		{"Added", "x-purell-test:abc", "x-purell-test:ABC"},
+		{"Disabled", "urn:ISBN:0451450523", "urn:ISBN:0451450523"},
+		{"Registered", "mailto:a@X.org", "mailto:a@x.org"},
+	}
+	for _, tc := range testcases {
+		if got := n.MustNormalizeString(tc.src); got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+
+	if got, want := MustNormalizeURLString("x-purell-test:abc", FlagSchemeSpecific), "x-purell-test:abc"; got != want {
+		t.Errorf("FAIL expected the registry to be untouched '%s', got '%s'", want, got)
+	}
+}
@@ -0,0 +1,122 @@
+package purell
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Well-known session identifier parameters, removed by FlagRemoveSessionIDs
+// from the query and from the path parameters.
+var defaultSessionParams = []string{
+	"aspsessionid*",
+	"cfid",
+	"cftoken",
+	"jsessionid",
+	"phpsessid",
+	"session_id",
+	"sessionid",
+	"sessid",
+}
+
+// Session identifier parameters with a name too generic to be removed
+// whatever their value, they are only removed if the value looks like a
+// session identifier, see isSessionIDValue.
+var weakSessionParams = []string{
+	"sid",
+}
+
+var sessionParams = mustSessionMatcher(defaultSessionParams)
+
+// DefaultSessionParams returns the names and patterns of the parameters
+// removed by FlagRemoveSessionIDs, unless a Normalizer is configured
+// otherwise using WithSessionParams. Additionally, the sid parameter is
+// removed if its value looks like a session identifier.
+func DefaultSessionParams() []string {
+	return append([]string(nil), defaultSessionParams...)
+}
+
+// WithSessionParams adds names and patterns of parameters to remove with
+// FlagRemoveSessionIDs, in addition to the default ones. Patterns use the
+// syntax of path.Match, e.g. "aspsessionid*". Unlike tracking parameters,
+// session parameters are matched case-insensitively.
+func WithSessionParams(patterns ...string) Option {
+	return func(n *Normalizer) error {
+		m, err := newSessionMatcher(append(DefaultSessionParams(), patterns...))
+		if err != nil {
+			return err
+		}
+		n.funcs[FlagRemoveSessionIDs] = func(u *url.URL) {
+			m.remove(u)
+		}
+		return nil
+	}
+}
+
+// sessionMatcher matches session identifier parameters, case-insensitively.
+type sessionMatcher struct {
+	names *paramMatcher
+	weak  *paramMatcher
+}
+
+func newSessionMatcher(patterns []string) (*sessionMatcher, error) {
+	lower := make([]string, len(patterns))
+	for i, p := range patterns {
+		lower[i] = strings.ToLower(p)
+	}
+	names, err := newParamMatcher(lower)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionMatcher{names: names, weak: mustParamMatcher(weakSessionParams)}, nil
+}
+
+func mustSessionMatcher(patterns []string) *sessionMatcher {
+	m, err := newSessionMatcher(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m *sessionMatcher) match(name, value string) bool {
+	name = strings.ToLower(name)
+	return m.names.match(name) || (m.weak.match(name) && isSessionIDValue(value))
+}
+
+// remove removes the session identifiers from the path parameters and the
+// query of the URL.
+func (m *sessionMatcher) remove(u *url.URL) {
+	filterMatrixParams(u, func(p string) bool {
+		name, value, _ := strings.Cut(p, "=")
+		return !m.match(name, value)
+	})
+	removeQueryPairs(u, func(p queryParam) bool {
+		return m.match(p.key, p.value())
+	})
+}
+
+// isSessionIDValue returns true if the value looks like a generated session
+// identifier: at least 16 characters among letters, digits, '-' and '_',
+// with both letters and digits.
+func isSessionIDValue(v string) bool {
+	if len(v) < 16 {
+		return false
+	}
+	var letter, digit bool
+	for _, c := range v {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+			letter = true
+		case '0' <= c && c <= '9':
+			digit = true
+		case c == '-' || c == '_':
+		default:
+			return false
+		}
+	}
+	return letter && digit
+}
+
+func removeSessionIDs(u *url.URL) {
+	sessionParams.remove(u)
+}
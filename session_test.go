@@ -0,0 +1,116 @@
+package purell
+
+import (
+	"testing"
+)
+
+func TestRemoveSessionIDs(t *testing.T) {
+	testcases := []struct {
+		nm   string
+		src  string
+		flgs NormalizationFlags
+		res  string
+	}{
+		{
+			"PathParam",
+			"http://host/shop;jsessionid=ABC123?x=1",
+			FlagRemoveSessionIDs,
+			"http://host/shop?x=1",
+		},
+		{
+			"PathParamUppercase",
+			"http://host/shop;JSESSIONID=ABC123/item;v=1",
+			FlagRemoveSessionIDs,
+			"http://host/shop/item;v=1",
+		},
+		{
+			"PathParamKeepsOthers",
+			"http://host/a;v=1;jsessionid=ABC;b=2/c",
+			FlagRemoveSessionIDs,
+			"http://host/a;v=1;b=2/c",
+		},
+		{
+			"PathParamEscapedSemicolon",
+			"http://host/a%3Bjsessionid=ABC;v=%3B;jsessionid=DEF",
+			FlagRemoveSessionIDs,
+			"http://host/a%3Bjsessionid=ABC;v=%3B",
+		},
+		{
+			"Query",
+			"http://host/path?PHPSESSID=abc&a=1&ASPSESSIONIDQACRCTDS=def",
+			FlagRemoveSessionIDs,
+			"http://host/path?a=1",
+		},
+		{
+			"AllRemoved",
+			"http://host/path?sessionid=1#frag",
+			FlagRemoveSessionIDs,
+			"http://host/path#frag",
+		},
+		{
+			"SidLooksLikeID",
+			"http://host/path?sid=9f86d081884c7d659a2f&a=1",
+			FlagRemoveSessionIDs,
+			"http://host/path?a=1",
+		},
+		{
+			"SidShort",
+			"http://host/path?sid=42&a=1",
+			FlagRemoveSessionIDs,
+			"http://host/path?sid=42&a=1",
+		},
+		{
+			"SidWord",
+			"http://host/path?sid=documentationpage",
+			FlagRemoveSessionIDs,
+			"http://host/path?sid=documentationpage",
+		},
+		{
+			"SidPath",
+			"http://host/path;sid=9F86D081884C7D659A2F",
+			FlagRemoveSessionIDs,
+			"http://host/path",
+		},
+		{
+			"BeforeDirectoryIndex",
+			"http://host/index.jsp;jsessionid=ABC",
+			FlagRemoveSessionIDs | FlagRemoveDirectoryIndex,
+			"http://host/",
+		},
+		{
+			"BeforeSort",
+			"http://host/?c=3&jsessionid=x&a=1",
+			FlagRemoveSessionIDs | FlagSortQuery,
+			"http://host/?a=1&c=3",
+		},
+		{
+			"NotSet",
+			"http://host/shop;jsessionid=ABC123?PHPSESSID=abc",
+			FlagsAllGreedy,
+			"http://host/shop;jsessionid=ABC123?PHPSESSID=abc",
+		},
+	}
+
+	for _, tc := range testcases {
+		if got, err := NormalizeURLString(tc.src, tc.flgs); err != nil {
+			t.Errorf("%s - FAIL : %s", tc.nm, err)
+		} else if got != tc.res {
+			t.Errorf("%s - FAIL expected '%s', got '%s'", tc.nm, tc.res, got)
+		}
+	}
+}
+
+func TestWithSessionParams(t *testing.T) {
+	n, err := NewNormalizer(FlagRemoveSessionIDs, WithSessionParams("token", "sess_*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "http://host/a?a=1"
+	if got := n.MustNormalizeString("http://host/a;Token=1?TOKEN=x&a=1&sess_id=2&jsessionid=3"); got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+
+	if _, err := NewNormalizer(FlagRemoveSessionIDs, WithSessionParams("sess_[")); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
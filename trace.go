@@ -0,0 +1,123 @@
+package purell
+
+import (
+	"net/url"
+	"strings"
+)
+
+// A Component is a set of parts of an URL.
+type Component uint
+
+const (
+	ComponentScheme Component = 1 << iota
+	ComponentUserinfo
+	ComponentHost
+	ComponentPort
+	ComponentPath // also covers the opaque part of the URL
+	ComponentQuery
+	ComponentFragment
+)
+
+var componentNames = []string{
+	"scheme",
+	"userinfo",
+	"host",
+	"port",
+	"path",
+	"query",
+	"fragment",
+}
+
+// String returns the names of the parts of the URL in the Component,
+// separated by "|", e.g. "host|path".
+func (c Component) String() string {
+	var names []string
+	for i, name := range componentNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// A TraceStep records a normalization step that changed the URL.
+type TraceStep struct {
+	// Name is the name of the step, e.g. "remove-trailing-slash" (see Step).
+	Name string
+
+	// Flag is the normalization flag of the step, or zero for custom steps.
+	Flag NormalizationFlags
+
+	// Before and After are the URL strings before and after the step ran.
+	Before string
+	After  string
+
+	// Changed is the set of parts of the URL modified by the step.
+	Changed Component
+}
+
+// NormalizeURLStringTrace is like NormalizeURLString, but also returns the
+// steps that changed the URL, in the order they ran.
+func NormalizeURLStringTrace(u string, f NormalizationFlags) (string, []TraceStep, error) {
+	n, err := NewNormalizer(f)
+	if err != nil {
+		return "", nil, err
+	}
+	return n.NormalizeStringTrace(u)
+}
+
+// NormalizeStringTrace is like NormalizeString, but also returns the steps
+// that changed the URL, in the order they ran.
+func (n *Normalizer) NormalizeStringTrace(u string) (string, []TraceStep, error) {
+	parsed, x, err := n.parse(u)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var trace []TraceStep
+	before := n.serialize(parsed, x)
+	for _, s := range n.steps {
+		prev := *parsed
+		s.fn(parsed)
+		if changed := diffComponents(&prev, parsed); changed != 0 {
+			after := n.serialize(parsed, x)
+			trace = append(trace, TraceStep{
+				Name:    s.name,
+				Flag:    s.flag,
+				Before:  before,
+				After:   after,
+				Changed: changed,
+			})
+			before = after
+		}
+	}
+	return before, trace, nil
+}
+
+// diffComponents returns the parts that differ between the two URL objects.
+func diffComponents(a, b *url.URL) Component {
+	var c Component
+	if a.Scheme != b.Scheme {
+		c |= ComponentScheme
+	}
+	if a.User.String() != b.User.String() {
+		c |= ComponentUserinfo
+	}
+	if a.Hostname() != b.Hostname() {
+		c |= ComponentHost
+	}
+	if a.Port() != b.Port() || (a.Host != b.Host && c&ComponentHost == 0) {
+		// An empty port separator counts as a change of the port
+		c |= ComponentPort
+	}
+	if a.Opaque != b.Opaque || a.Path != b.Path || a.RawPath != b.RawPath {
+		c |= ComponentPath
+	}
+	if a.RawQuery != b.RawQuery || a.ForceQuery != b.ForceQuery {
+		c |= ComponentQuery
+	}
+	if a.Fragment != b.Fragment || a.RawFragment != b.RawFragment {
+		c |= ComponentFragment
+	}
+	return c
+}
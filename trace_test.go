@@ -0,0 +1,85 @@
+package purell
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeURLStringTrace(t *testing.T) {
+	src := "http://www.Example.com:80/a/./b/?utm_source=x&c=3#frag"
+	flgs := FlagsSafe | FlagRemoveDotSegments | FlagRemoveTrackingParams | FlagRemoveFragment | FlagRemoveTrailingSlash
+	want := []TraceStep{
+		{"remove-default-port", FlagRemoveDefaultPort, "http://www.example.com:80/a/./b/?utm_source=x&c=3#frag", "http://www.example.com/a/./b/?utm_source=x&c=3#frag", ComponentPort},
+		{"remove-dot-segments", FlagRemoveDotSegments, "http://www.example.com/a/./b/?utm_source=x&c=3#frag", "http://www.example.com/a/b/?utm_source=x&c=3#frag", ComponentPath},
+		{"remove-fragment", FlagRemoveFragment, "http://www.example.com/a/b/?utm_source=x&c=3#frag", "http://www.example.com/a/b/?utm_source=x&c=3", ComponentFragment},
+		{"remove-tracking-params", FlagRemoveTrackingParams, "http://www.example.com/a/b/?utm_source=x&c=3", "http://www.example.com/a/b/?c=3", ComponentQuery},
+		{"remove-trailing-slash", FlagRemoveTrailingSlash, "http://www.example.com/a/b/?c=3", "http://www.example.com/a/b?c=3", ComponentPath},
+	}
+
+	got, trace, err := NormalizeURLStringTrace(src, flgs)
+	if err != nil {
+		t.Fatalf("FAIL : %s", err)
+	}
+	if res := MustNormalizeURLString(src, flgs); got != res {
+		t.Errorf("FAIL expected '%s', got '%s'", res, got)
+	}
+	if len(trace) != len(want) {
+		t.Fatalf("FAIL expected %d steps, got %d: %v", len(want), len(trace), trace)
+	}
+	for i, s := range trace {
+		if s != want[i] {
+			t.Errorf("step %d - FAIL expected %+v, got %+v", i, want[i], s)
+		}
+	}
+}
+
+func TestNormalizeStringTraceSteps(t *testing.T) {
+	n := MustNewNormalizer(FlagLowercaseHost|FlagRemoveEmptyPortSeparator|FlagRemoveWWW,
+		WithStep(Step{Name: "noop", Func: func(*url.URL) {}}),
+		WithStep(Step{Name: "add-query", Func: func(u *url.URL) { u.RawQuery = "a=1" }}),
+	)
+	got, trace, err := n.NormalizeStringTrace("http://www.host:/")
+	if err != nil {
+		t.Fatalf("FAIL : %s", err)
+	}
+	if want := "http://host/?a=1"; got != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, got)
+	}
+
+	want := []struct {
+		name    string
+		changed Component
+	}{
+		{"remove-www", ComponentHost},
+		{"remove-empty-port-separator", ComponentPort},
+		{"add-query", ComponentQuery},
+	}
+	if len(trace) != len(want) {
+		t.Fatalf("FAIL expected %d steps, got %d: %v", len(want), len(trace), trace)
+	}
+	for i, s := range trace {
+		if s.Name != want[i].name || s.Changed != want[i].changed {
+			t.Errorf("step %d - FAIL expected %s (%s), got %s (%s)", i, want[i].name, want[i].changed, s.Name, s.Changed)
+		}
+	}
+	if trace[2].Flag != 0 || trace[2].Before != "http://host/" {
+		t.Errorf("custom step - FAIL got %+v", trace[2])
+	}
+}
+
+func TestComponentString(t *testing.T) {
+	testcases := []struct {
+		c   Component
+		res string
+	}{
+		{0, ""},
+		{ComponentScheme, "scheme"},
+		{ComponentHost | ComponentPath, "host|path"},
+		{ComponentScheme | ComponentUserinfo | ComponentHost | ComponentPort | ComponentPath | ComponentQuery | ComponentFragment, "scheme|userinfo|host|port|path|query|fragment"},
+	}
+	for _, tc := range testcases {
+		if got := tc.c.String(); got != tc.res {
+			t.Errorf("FAIL expected '%s', got '%s'", tc.res, got)
+		}
+	}
+}
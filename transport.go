@@ -0,0 +1,113 @@
+package purell
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ErrDuplicateURL is returned by the RoundTripper of CanonicalTransport for
+// requests whose normalized URL is already in its seen-set.
+var ErrDuplicateURL = errors.New("purell: duplicate URL")
+
+// A SeenSet records the normalized URLs of the requests sent by the
+// RoundTripper of CanonicalTransport. It must be safe for concurrent use.
+type SeenSet interface {
+	// Seen records the normalized URL string, and returns true if it was
+	// already recorded.
+	Seen(u string) bool
+}
+
+// NewSeenSet returns an in-memory SeenSet, that records the 128-bit
+// fingerprints of the URLs.
+func NewSeenSet() SeenSet {
+	return &seenSet{seen: make(map[[16]byte]bool)}
+}
+
+type seenSet struct {
+	mu   sync.Mutex
+	seen map[[16]byte]bool
+}
+
+func (s *seenSet) Seen(u string) bool {
+	fp := fingerprint128(u)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[fp] {
+		return true
+	}
+	s.seen[fp] = true
+	return false
+}
+
+// A TransportOption configures the RoundTripper returned by
+// CanonicalTransport.
+type TransportOption func(*canonicalTransport)
+
+// WithSeenSet makes the RoundTripper fail with ErrDuplicateURL, without
+// sending the request, if its normalized URL is already in the seen-set.
+// Note that this applies to the requests of redirects too.
+func WithSeenSet(s SeenSet) TransportOption {
+	return func(t *canonicalTransport) {
+		t.seen = s
+	}
+}
+
+// CanonicalTransport returns a RoundTripper that normalizes the URL of each
+// request before sending it with next, or http.DefaultTransport if next is
+// nil. The original URL of the request is recorded in the context of the
+// request that is sent, see OriginalURL. The request passed to RoundTrip is
+// not modified.
+func CanonicalTransport(next http.RoundTripper, n *Normalizer, opts ...TransportOption) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &canonicalTransport{next: next, n: n}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+type canonicalTransport struct {
+	next http.RoundTripper
+	n    *Normalizer
+	seen SeenSet
+}
+
+func (t *canonicalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	normalized, err := t.n.NormalizeString(req.URL.String())
+	if err == nil && t.seen != nil && t.seen.Seen(normalized) {
+		err = ErrDuplicateURL
+	}
+	var u *url.URL
+	if err == nil {
+		u, err = url.Parse(normalized)
+	}
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	original := *req.URL
+	req2 := req.Clone(context.WithValue(req.Context(), originalURLKey{}, &original))
+	req2.URL = u
+	if req.Host == req.URL.Host {
+		req2.Host = u.Host
+	}
+	return t.next.RoundTrip(req2)
+}
+
+type originalURLKey struct{}
+
+// OriginalURL returns the URL of the request before it was normalized by
+// the RoundTripper of CanonicalTransport, from the context of the request
+// that was sent (e.g. resp.Request.Context()).
+func OriginalURL(ctx context.Context) (*url.URL, bool) {
+	u, ok := ctx.Value(originalURLKey{}).(*url.URL)
+	return u, ok
+}
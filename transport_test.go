@@ -0,0 +1,118 @@
+package purell
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCanonicalTransport(t *testing.T) {
+	var sent *http.Request
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	rt := CanonicalTransport(next, MustNewNormalizer(FlagsUsuallySafeGreedy|FlagSortQuery|FlagRemoveFragment))
+
+	req, _ := http.NewRequest("GET", "HTTP://Example.com:80/a/./b/?b=2&a=1#frag", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("FAIL : %s", err)
+	}
+	resp.Body.Close()
+
+	if want := "http://example.com/a/b?a=1&b=2"; sent.URL.String() != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, sent.URL)
+	}
+	if sent.Host != "example.com" {
+		t.Errorf("FAIL expected host 'example.com', got '%s'", sent.Host)
+	}
+	if want := "http://Example.com:80/a/./b/?b=2&a=1#frag"; req.URL.String() != want {
+		t.Errorf("FAIL expected the original request to be untouched, got '%s'", req.URL)
+	}
+	if u, ok := OriginalURL(resp.Request.Context()); !ok || u.String() != req.URL.String() {
+		t.Errorf("FAIL expected original URL '%s', got '%v'", req.URL, u)
+	}
+	if _, ok := OriginalURL(req.Context()); ok {
+		t.Errorf("FAIL expected no original URL in the context of the original request")
+	}
+}
+
+func TestCanonicalTransportHost(t *testing.T) {
+	var sent *http.Request
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := CanonicalTransport(next, MustNewNormalizer(FlagsSafe))
+
+	// An explicit Host header is kept
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:80/", nil)
+	req.Host = "virtual.example.com"
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("FAIL : %s", err)
+	}
+	if sent.Host != "virtual.example.com" || sent.URL.Host != "127.0.0.1" {
+		t.Errorf("FAIL expected 'virtual.example.com' and '127.0.0.1', got '%s' and '%s'", sent.Host, sent.URL.Host)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCanonicalTransportSeenSet(t *testing.T) {
+	var count int
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		count++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := CanonicalTransport(next, MustNewNormalizer(FlagsUsuallySafeGreedy), WithSeenSet(NewSeenSet()))
+
+	for i, src := range []string{"http://example.com/a/", "HTTP://EXAMPLE.com/a", "http://example.com/b"} {
+		body := &closeRecorder{Reader: strings.NewReader("body")}
+		req, _ := http.NewRequest("POST", src, body)
+		_, err := rt.RoundTrip(req)
+		if dup := i == 1; dup != errors.Is(err, ErrDuplicateURL) {
+			t.Errorf("%s - FAIL expected duplicate %t, got error %v", src, dup, err)
+		} else if dup && !body.closed {
+			t.Errorf("%s - FAIL expected the body to be closed", src)
+		}
+	}
+	if count != 2 {
+		t.Errorf("FAIL expected 2 requests, got %d", count)
+	}
+}
+
+func TestCanonicalTransportClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RequestURI())
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: CanonicalTransport(nil, MustNewNormalizer(FlagsSafe|FlagSortQuery))}
+	resp, err := client.Get(srv.URL + "/path?b=2&a=1")
+	if err != nil {
+		t.Fatalf("FAIL : %s", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if want := "/path?a=1&b=2"; string(body) != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, body)
+	}
+}
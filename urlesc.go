@@ -21,6 +21,7 @@ const (
 	encodeUserPassword
 	encodeQueryComponent
 	encodeFragment
+	encodeZone
 )
 
 // Return true if the specified character should be escaped when
@@ -62,6 +63,10 @@ func shouldEscape(c byte, mode encoding) bool {
 			// The RFC text is silent but the grammar allows
 			// everything, so escape nothing but #
 			return c == '#'
+
+		case encodeZone: // RFC 6874 §2
+			// Zone identifiers only allow unreserved characters.
+			return true
 		}
 	}
 
@@ -106,6 +111,16 @@ func escape(s string, mode encoding) string {
 	return string(t)
 }
 
+// escapeHost escapes the zone identifier of an IPv6 literal host, e.g.
+// [fe80::1%eth0] becomes [fe80::1%25eth0]. Other hosts are returned as is.
+func escapeHost(h string) string {
+	i, end := strings.IndexByte(h, '%'), strings.LastIndexByte(h, ']')
+	if !strings.HasPrefix(h, "[") || i < 0 || end < i {
+		return h
+	}
+	return h[:i] + "%25" + escape(h[i+1:end], encodeZone) + h[end:]
+}
+
 var uiReplacer = strings.NewReplacer(
 	"%21", "!",
 	"%27", "'",
@@ -139,6 +154,12 @@ func unescapeUserinfo(s string) string {
 //	- if u.RawQuery is empty, ?query is omitted.
 //	- if u.Fragment is empty, #fragment is omitted.
 func escapeURL(u *url.URL) string {
+	return escapeURLSemicolons(u, false)
+}
+
+// escapeURLSemicolons is like escapeURL, but keeps the escaped semicolons of
+// the path if keepSemicolons is true, see escapePath.
+func escapeURLSemicolons(u *url.URL, keepSemicolons bool) string {
 	var buf bytes.Buffer
 	if u.Scheme != "" {
 		buf.WriteString(u.Scheme)
@@ -154,13 +175,13 @@ func escapeURL(u *url.URL) string {
 				buf.WriteByte('@')
 			}
 			if h := u.Host; h != "" {
-				buf.WriteString(h)
+				buf.WriteString(escapeHost(h))
 			}
 		}
 		if u.Path != "" && u.Path[0] != '/' && u.Host != "" {
 			buf.WriteByte('/')
 		}
-		buf.WriteString(escape(u.Path, encodePath))
+		buf.WriteString(escapePath(u, keepSemicolons))
 	}
 	if u.RawQuery != "" {
 		buf.WriteByte('?')
@@ -172,3 +193,30 @@ func escapeURL(u *url.URL) string {
 	}
 	return buf.String()
 }
+
+// escapePath escapes the path of the URL. If keepSemicolons is true, the
+// escaped semicolons of a valid RawPath are kept, as they are data and not
+// separators of the matrix parameters.
+func escapePath(u *url.URL, keepSemicolons bool) string {
+	if !keepSemicolons || u.RawPath == "" || !strings.Contains(u.Path, ";") {
+		return escape(u.Path, encodePath)
+	}
+	if p, err := url.PathUnescape(u.RawPath); err != nil || p != u.Path {
+		return escape(u.Path, encodePath)
+	}
+
+	var buf bytes.Buffer
+	raw := u.RawPath
+	for i := 0; i+2 < len(raw); i++ {
+		if raw[i] == '%' && raw[i+1] == '3' && (raw[i+2] == 'B' || raw[i+2] == 'b') {
+			seg, _ := url.PathUnescape(raw[:i])
+			buf.WriteString(escape(seg, encodePath))
+			buf.WriteString("%3B")
+			raw = raw[i+3:]
+			i = -1
+		}
+	}
+	seg, _ := url.PathUnescape(raw)
+	buf.WriteString(escape(seg, encodePath))
+	return buf.String()
+}
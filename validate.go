@@ -0,0 +1,51 @@
+package purell
+
+import (
+	"fmt"
+)
+
+// Pairs of flags that undo each other, the result of combining them only
+// depends on the order in which they are applied.
+var conflictingFlags = [][2]NormalizationFlags{
+	{FlagRemoveTrailingSlash, FlagAddTrailingSlash},
+	{FlagRemoveWWW, FlagAddWWW},
+	{FlagRemoveDefaultPort, FlagAddDefaultPort},
+}
+
+// A FlagConflictError is returned for a combination of contradictory
+// normalization flags.
+type FlagConflictError struct {
+	Flags [2]NormalizationFlags
+}
+
+func (e *FlagConflictError) Error() string {
+	return fmt.Sprintf("purell: conflicting normalization flags %s and %s", e.Flags[0], e.Flags[1])
+}
+
+// Validate returns a *FlagConflictError if the flags contain contradictory
+// normalizations, such as FlagAddTrailingSlash and FlagRemoveTrailingSlash.
+func Validate(f NormalizationFlags) error {
+	for _, c := range conflictingFlags {
+		if f&c[0] == c[0] && f&c[1] == c[1] {
+			return &FlagConflictError{Flags: c}
+		}
+	}
+	return nil
+}
+
+// NormalizeURLStringStrict is like NormalizeURLString, but returns an error
+// if the flags are not valid, see Validate.
+func NormalizeURLStringStrict(u string, f NormalizationFlags) (string, error) {
+	if err := Validate(f); err != nil {
+		return "", err
+	}
+	return NormalizeURLString(u, f)
+}
+
+// WithValidation makes NewNormalizer return an error if its flags are not
+// valid, see Validate.
+func WithValidation() Option {
+	return func(n *Normalizer) error {
+		return Validate(n.flags)
+	}
+}
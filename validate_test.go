@@ -0,0 +1,60 @@
+package purell
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		nm       string
+		flgs     NormalizationFlags
+		conflict [2]NormalizationFlags
+	}{
+		{"None", 0, [2]NormalizationFlags{}},
+		{"AllGreedy", FlagsAllGreedy, [2]NormalizationFlags{}},
+		{"AllNonGreedy", FlagsAllNonGreedy, [2]NormalizationFlags{}},
+		{"TrailingSlash", FlagsSafe | FlagAddTrailingSlash | FlagRemoveTrailingSlash, [2]NormalizationFlags{FlagRemoveTrailingSlash, FlagAddTrailingSlash}},
+		{"WWW", FlagsUnsafeGreedy | FlagAddWWW, [2]NormalizationFlags{FlagRemoveWWW, FlagAddWWW}},
+		{"DefaultPort", FlagsSafe | FlagAddDefaultPort, [2]NormalizationFlags{FlagRemoveDefaultPort, FlagAddDefaultPort}},
+		{"AddDefaultPort", FlagsSafe&^FlagRemoveDefaultPort | FlagAddDefaultPort, [2]NormalizationFlags{}},
+		{"GreedyAndNonGreedy", FlagsAllGreedy | FlagsAllNonGreedy, [2]NormalizationFlags{FlagRemoveTrailingSlash, FlagAddTrailingSlash}},
+	}
+
+	for _, tc := range testcases {
+		err := Validate(tc.flgs)
+		var conflict *FlagConflictError
+		if tc.conflict[0] == 0 {
+			if err != nil {
+				t.Errorf("%s - FAIL : %s", tc.nm, err)
+			}
+		} else if !errors.As(err, &conflict) {
+			t.Errorf("%s - FAIL expected conflict, got %v", tc.nm, err)
+		} else if conflict.Flags != tc.conflict {
+			t.Errorf("%s - FAIL expected conflict between %s, got %s", tc.nm, tc.conflict, conflict.Flags)
+		}
+	}
+
+	want := "purell: conflicting normalization flags remove-www and add-www"
+	if err := Validate(FlagRemoveWWW | FlagAddWWW); err == nil || err.Error() != want {
+		t.Errorf("FAIL expected '%s', got '%v'", want, err)
+	}
+}
+
+func TestNormalizeURLStringStrict(t *testing.T) {
+	if got, err := NormalizeURLStringStrict("http://www.host/a/", FlagsUsuallySafeGreedy|FlagRemoveWWW); err != nil {
+		t.Errorf("FAIL : %s", err)
+	} else if want := "http://host/a"; got != want {
+		t.Errorf("FAIL expected '%s', got '%s'", want, got)
+	}
+	if _, err := NormalizeURLStringStrict("http://host/a/", FlagAddTrailingSlash|FlagRemoveTrailingSlash); err == nil {
+		t.Errorf("FAIL expected error")
+	}
+
+	if _, err := NewNormalizer(FlagsUnsafeGreedy|FlagAddWWW, WithValidation()); err == nil {
+		t.Errorf("FAIL expected error")
+	}
+	if _, err := NewNormalizer(FlagsUnsafeGreedy | FlagAddWWW); err != nil {
+		t.Errorf("FAIL : %s", err)
+	}
+}
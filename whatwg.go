@@ -0,0 +1,956 @@
+package purell
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// This file implements the parser and serializer of the WHATWG URL Standard
+// (https://url.spec.whatwg.org/), which is how web browsers read URLs.
+
+// Default ports of the special schemes of the URL Standard. The file scheme
+// is special but has no default port.
+var whatwgSpecialSchemes = map[string]string{
+	"ftp":   "21",
+	"file":  "",
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// The IDNA profile of the "domain to ASCII" algorithm of the URL Standard.
+var whatwgIDNA = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.CheckJoiners(true),
+	idna.CheckHyphens(false),
+	idna.StrictDomainName(false),
+	idna.Transitional(false),
+	idna.VerifyDNSLength(false),
+)
+
+// errWHATWGFailure is returned when an input fails to parse as per the URL
+// Standard.
+var errWHATWGFailure = errors.New("invalid URL")
+
+// whatwgURL is an URL record as defined by the URL Standard. Its components
+// are kept percent-encoded, exactly as they are serialized.
+type whatwgURL struct {
+	scheme     string
+	username   string
+	password   string
+	host       string
+	hasHost    bool // the host is null if false
+	port       string
+	path       []string
+	opaquePath string
+	opaque     bool // the URL has an opaque path if true
+	query      string
+	hasQuery   bool
+	fragment   string
+	hasFrag    bool
+}
+
+func (w *whatwgURL) special() bool {
+	_, ok := whatwgSpecialSchemes[w.scheme]
+	return ok
+}
+
+// shortenPath removes the last path segment, unless it is the drive letter of
+// a file URL.
+func (w *whatwgURL) shortenPath() {
+	if w.scheme == "file" && len(w.path) == 1 && isNormalizedWindowsDriveLetter(w.path[0]) {
+		return
+	}
+	if len(w.path) > 0 {
+		w.path = w.path[:len(w.path)-1]
+	}
+}
+
+// pathname returns the serialization of the path.
+func (w *whatwgURL) pathname() string {
+	if w.opaque {
+		return w.opaquePath
+	}
+	var buf strings.Builder
+	for _, s := range w.path {
+		buf.WriteByte('/')
+		buf.WriteString(s)
+	}
+	return buf.String()
+}
+
+// hostport returns the serialization of the host and port.
+func (w *whatwgURL) hostport() string {
+	if w.port != "" {
+		return w.host + ":" + w.port
+	}
+	return w.host
+}
+
+// href returns the serialization of the URL.
+func (w *whatwgURL) href() string {
+	var buf strings.Builder
+	buf.WriteString(w.scheme)
+	buf.WriteByte(':')
+	if w.hasHost {
+		buf.WriteString("//")
+		if w.username != "" || w.password != "" {
+			buf.WriteString(w.username)
+			if w.password != "" {
+				buf.WriteByte(':')
+				buf.WriteString(w.password)
+			}
+			buf.WriteByte('@')
+		}
+		buf.WriteString(w.hostport())
+	} else if !w.opaque && len(w.path) > 1 && w.path[0] == "" {
+		buf.WriteString("/.")
+	}
+	buf.WriteString(w.pathname())
+	if w.hasQuery {
+		buf.WriteByte('?')
+		buf.WriteString(w.query)
+	}
+	if w.hasFrag {
+		buf.WriteByte('#')
+		buf.WriteString(w.fragment)
+	}
+	return buf.String()
+}
+
+// whatwgExtra holds the parts of an URL record that an url.URL cannot
+// represent.
+type whatwgExtra struct {
+	userinfo  string // percent-encoded userinfo, as serialized
+	forceFrag bool   // the fragment is empty but not null
+}
+
+// toURL returns the url.URL equivalent of the URL record. The Path and
+// Fragment fields are unescaped, with RawPath and RawFragment holding the
+// original encoding.
+func (w *whatwgURL) toURL() (*url.URL, whatwgExtra) {
+	u := &url.URL{Scheme: w.scheme, RawQuery: w.query, ForceQuery: w.hasQuery && w.query == ""}
+	var x whatwgExtra
+
+	if w.opaque {
+		u.Opaque = w.opaquePath
+	} else {
+		u.RawPath = w.pathname()
+		u.Path = percentDecode(u.RawPath)
+	}
+	if w.hasHost {
+		u.Host = w.hostport()
+		if w.username != "" || w.password != "" {
+			x.userinfo = w.username
+			if w.password != "" {
+				x.userinfo += ":" + w.password
+				u.User = url.UserPassword(percentDecode(w.username), percentDecode(w.password))
+			} else {
+				u.User = url.User(percentDecode(w.username))
+			}
+		}
+	} else {
+		u.OmitHost = true
+	}
+	if w.hasFrag {
+		u.RawFragment = w.fragment
+		u.Fragment = percentDecode(w.fragment)
+		x.forceFrag = w.fragment == ""
+	}
+	return u, x
+}
+
+// ParseWHATWG parses the URL string as per the WHATWG URL Standard, the way
+// web browsers do, e.g. ignoring leading and trailing spaces, tabs and
+// newlines, treating backslashes as slashes for special schemes and
+// decoding IPv4 hosts. Unlike url.Parse, relative references are rejected.
+//
+// The Path and Fragment of the returned URL are unescaped, with RawPath and
+// RawFragment holding the encoding of the standard. The host is in its
+// ASCII form.
+func ParseWHATWG(rawurl string) (*url.URL, error) {
+	w, err := parseWHATWG(rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	u, _ := w.toURL()
+	return u, nil
+}
+
+// NormalizeURLStringWHATWG is like NormalizeURLString, but parses and
+// serializes the URL as per the WHATWG URL Standard. Without flags, the
+// result is the serialization a web browser would produce. Because the
+// standard already lowercases hosts and converts them to their ASCII form,
+// decodes IPv4 hosts and removes default ports, those flags have no
+// additional effect.
+//
+// The escape flags apply to the userinfo, path, query and fragment:
+// FlagUppercaseEscapes uppercases the hexadecimal digits of the escapes,
+// FlagDecodeUnnecessaryEscapes decodes the escaped unreserved characters of
+// RFC 3986 (letters, digits, '-', '.', '_' and '~'), and
+// FlagEncodeNecessaryEscapes escapes the characters that RFC 3986 does not
+// allow in the component, such as '|' or '^', and the '%' that do not start
+// an escape.
+func NormalizeURLStringWHATWG(u string, f NormalizationFlags) (string, error) {
+	w, err := parseWHATWG(u, nil)
+	if err != nil {
+		return "", err
+	}
+	parsed, x := w.toURL()
+	applyFlags(parsed, f)
+	return serializeWHATWG(parsed, f, x), nil
+}
+
+// WithWHATWG makes the Normalizer parse and serialize URLs as per the WHATWG
+// URL Standard, like NormalizeURLStringWHATWG.
+func WithWHATWG() Option {
+	return func(n *Normalizer) error {
+		n.whatwg = true
+		return nil
+	}
+}
+
+// serializeWHATWG serializes the URL as per the URL Standard. Components
+// that were not modified since parsing keep their original encoding.
+func serializeWHATWG(u *url.URL, f NormalizationFlags, x whatwgExtra) string {
+	var buf strings.Builder
+	if u.Scheme != "" {
+		buf.WriteString(u.Scheme)
+		buf.WriteByte(':')
+	}
+	if u.Opaque != "" {
+		buf.WriteString(normalizeEscapes(u.Opaque, f, isPathChar))
+	} else {
+		_, special := whatwgSpecialSchemes[u.Scheme]
+		path := u.RawPath
+		if percentDecode(path) != u.Path {
+			path = percentEncode(u.Path, func(c byte) bool {
+				return inPathSet(c) || c == '%' || (special && c == '\\')
+			})
+		}
+		path = normalizeEscapes(path, f, isPathChar)
+
+		if !u.OmitHost || u.Host != "" || u.User != nil {
+			buf.WriteString("//")
+			if u.User != nil {
+				buf.WriteString(normalizeEscapes(whatwgUserinfo(u.User, x.userinfo), f, isUserinfoChar))
+				buf.WriteByte('@')
+			}
+			buf.WriteString(u.Host)
+		} else if strings.HasPrefix(path, "//") {
+			buf.WriteString("/.")
+		}
+		if path != "" && path[0] != '/' && (u.Host != "" || special) {
+			buf.WriteByte('/')
+		}
+		buf.WriteString(path)
+	}
+	if u.RawQuery != "" || (u.ForceQuery && f&FlagRemoveEmptyQuerySeparator == 0) {
+		buf.WriteByte('?')
+		buf.WriteString(normalizeEscapes(u.RawQuery, f, isQueryChar))
+	}
+	if u.Fragment != "" || (x.forceFrag && f&FlagRemoveFragment == 0) {
+		frag := u.RawFragment
+		if percentDecode(frag) != u.Fragment {
+			frag = percentEncode(u.Fragment, func(c byte) bool {
+				return inFragmentSet(c) || c == '%'
+			})
+		}
+		buf.WriteByte('#')
+		buf.WriteString(normalizeEscapes(frag, f, isQueryChar))
+	}
+	return buf.String()
+}
+
+// normalizeEscapes applies the escape flags to the percent-encoded
+// component s. The allowed function reports the ASCII characters that
+// RFC 3986 allows unescaped in the component.
+func normalizeEscapes(s string, f NormalizationFlags, allowed func(byte) bool) string {
+	const escapeFlags = FlagUppercaseEscapes | FlagDecodeUnnecessaryEscapes | FlagEncodeNecessaryEscapes
+	if f&escapeFlags == 0 {
+		return s
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if f&FlagDecodeUnnecessaryEscapes != 0 && isUnreserved(b) {
+				buf.WriteByte(b)
+			} else if f&FlagUppercaseEscapes != 0 {
+				buf.WriteByte('%')
+				buf.WriteString(strings.ToUpper(s[i+1 : i+3]))
+			} else {
+				buf.WriteString(s[i : i+3])
+			}
+			i += 2
+		case f&FlagEncodeNecessaryEscapes != 0 && c < utf8.RuneSelf && (c == '%' || !allowed(c)):
+			buf.WriteByte('%')
+			buf.WriteByte("0123456789ABCDEF"[c>>4])
+			buf.WriteByte("0123456789ABCDEF"[c&15])
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// The characters of RFC 3986, for normalizeEscapes.
+
+func isUnreserved(c byte) bool {
+	return isASCIIAlpha(rune(c)) || isASCIIDigit(rune(c)) || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isUserinfoChar(c byte) bool {
+	return isUnreserved(c) || strings.IndexByte("!$&'()*+,;=:", c) >= 0
+}
+
+func isPathChar(c byte) bool {
+	return isUserinfoChar(c) || c == '@' || c == '/'
+}
+
+func isQueryChar(c byte) bool {
+	return isPathChar(c) || c == '?'
+}
+
+// whatwgUserinfo returns the percent-encoded userinfo, keeping the original
+// encoding if the userinfo was not modified since parsing.
+func whatwgUserinfo(ui *url.Userinfo, raw string) string {
+	username, password := raw, ""
+	hasPassword := false
+	if i := strings.IndexByte(raw, ':'); i >= 0 {
+		username, password, hasPassword = raw[:i], raw[i+1:], true
+	}
+	p, ok := ui.Password()
+	if percentDecode(username) == ui.Username() && percentDecode(password) == p && hasPassword == ok {
+		return raw
+	}
+
+	encode := func(s string) string {
+		return percentEncode(s, func(c byte) bool {
+			return inUserinfoSet(c) || c == '%'
+		})
+	}
+	if ok && p != "" {
+		return encode(ui.Username()) + ":" + encode(p)
+	}
+	return encode(ui.Username())
+}
+
+// The percent-encode sets of the URL Standard, for ASCII bytes. Non-ASCII
+// bytes are always encoded.
+
+func inC0ControlSet(c byte) bool {
+	return c < 0x20 || c > 0x7E
+}
+
+func inFragmentSet(c byte) bool {
+	return inC0ControlSet(c) || c == ' ' || c == '"' || c == '<' || c == '>' || c == '`'
+}
+
+func inQuerySet(c byte) bool {
+	return inC0ControlSet(c) || c == ' ' || c == '"' || c == '#' || c == '<' || c == '>'
+}
+
+func inSpecialQuerySet(c byte) bool {
+	return inQuerySet(c) || c == '\''
+}
+
+func inPathSet(c byte) bool {
+	return inQuerySet(c) || c == '?' || c == '`' || c == '{' || c == '}'
+}
+
+func inUserinfoSet(c byte) bool {
+	return inPathSet(c) || c == '/' || c == ':' || c == ';' || c == '=' || c == '@' || ('[' <= c && c <= '^') || c == '|'
+}
+
+// percentEncode encodes the bytes of the string that are in the set.
+func percentEncode(s string, set func(byte) bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; set(c) {
+			buf.WriteByte('%')
+			buf.WriteByte("0123456789ABCDEF"[c>>4])
+			buf.WriteByte("0123456789ABCDEF"[c&15])
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// percentDecode decodes the valid percent-encoded bytes of the string,
+// leaving invalid sequences as they are.
+func percentDecode(s string) string {
+	if strings.IndexByte(s, '%') < 0 {
+		return s
+	}
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			buf = append(buf, unhex(s[i+1])<<4|unhex(s[i+2]))
+			i += 2
+		} else {
+			buf = append(buf, s[i])
+		}
+	}
+	return string(buf)
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+func isASCIIAlpha(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
+
+func isASCIIDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isWindowsDriveLetter(s string) bool {
+	return len(s) == 2 && isASCIIAlpha(rune(s[0])) && (s[1] == ':' || s[1] == '|')
+}
+
+func isNormalizedWindowsDriveLetter(s string) bool {
+	return isWindowsDriveLetter(s) && s[1] == ':'
+}
+
+// startsWithWindowsDriveLetter reports whether the code points start with a
+// Windows drive letter followed by nothing or by a path delimiter.
+func startsWithWindowsDriveLetter(rs []rune) bool {
+	if len(rs) < 2 || !isASCIIAlpha(rs[0]) || (rs[1] != ':' && rs[1] != '|') {
+		return false
+	}
+	return len(rs) == 2 || rs[2] == '/' || rs[2] == '\\' || rs[2] == '?' || rs[2] == '#'
+}
+
+func isSingleDot(s string) bool {
+	return s == "." || strings.EqualFold(s, "%2e")
+}
+
+func isDoubleDot(s string) bool {
+	switch strings.ToLower(s) {
+	case "..", ".%2e", "%2e.", "%2e%2e":
+		return true
+	}
+	return false
+}
+
+// appendEncoded appends the UTF-8 encoding of the rune to the buffer,
+// percent-encoding it if it is in the set.
+func appendEncoded(buf []byte, r rune, set func(byte) bool) []byte {
+	var b [utf8.UTFMax]byte
+	n := utf8.EncodeRune(b[:], r)
+	for _, c := range b[:n] {
+		if set(c) {
+			buf = append(buf, '%', "0123456789ABCDEF"[c>>4], "0123456789ABCDEF"[c&15])
+		} else {
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// The states of the basic URL parser.
+type whatwgState int
+
+const (
+	stateSchemeStart whatwgState = iota
+	stateScheme
+	stateNoScheme
+	stateSpecialRelativeOrAuthority
+	statePathOrAuthority
+	stateRelative
+	stateRelativeSlash
+	stateSpecialAuthoritySlashes
+	stateSpecialAuthorityIgnoreSlashes
+	stateAuthority
+	stateHost
+	statePort
+	stateFile
+	stateFileSlash
+	stateFileHost
+	statePathStart
+	statePath
+	stateOpaquePath
+	stateQuery
+	stateFragment
+)
+
+// eof is the code point used by the parser past the end of the input.
+const eof = -1
+
+// parseWHATWG implements the basic URL parser of the URL Standard, without
+// state override. The base may be nil.
+func parseWHATWG(input string, base *whatwgURL) (*whatwgURL, error) {
+	input = strings.TrimFunc(input, func(r rune) bool { return r <= 0x20 })
+	input = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, input)
+
+	var (
+		w               = &whatwgURL{}
+		rs              = []rune(input)
+		state           = stateSchemeStart
+		buf             []byte
+		atSignSeen      bool
+		insideBrackets  bool
+		passwordSeen    bool
+		remainingStarts = func(p int, s string) bool {
+			return p+1 < len(rs) && strings.HasPrefix(string(rs[p+1:]), s)
+		}
+	)
+
+	for p := 0; p <= len(rs); p++ {
+		c := rune(eof)
+		if p < len(rs) {
+			c = rs[p]
+		}
+
+		switch state {
+		case stateSchemeStart:
+			if isASCIIAlpha(c) {
+				buf = append(buf, byte(c|0x20))
+				state = stateScheme
+			} else {
+				state = stateNoScheme
+				p--
+			}
+
+		case stateScheme:
+			if isASCIIAlpha(c) || isASCIIDigit(c) || c == '+' || c == '-' || c == '.' {
+				if isASCIIAlpha(c) {
+					c |= 0x20
+				}
+				buf = append(buf, byte(c))
+			} else if c == ':' {
+				w.scheme = string(buf)
+				buf = buf[:0]
+				switch {
+				case w.scheme == "file":
+					state = stateFile
+				case w.special() && base != nil && base.scheme == w.scheme:
+					state = stateSpecialRelativeOrAuthority
+				case w.special():
+					state = stateSpecialAuthoritySlashes
+				case remainingStarts(p, "/"):
+					state = statePathOrAuthority
+					p++
+				default:
+					w.opaque = true
+					state = stateOpaquePath
+				}
+			} else {
+				buf = buf[:0]
+				state = stateNoScheme
+				p = -1
+			}
+
+		case stateNoScheme:
+			switch {
+			case base == nil || (base.opaque && c != '#'):
+				return nil, errWHATWGFailure
+			case base.opaque && c == '#':
+				w.scheme = base.scheme
+				w.opaque, w.opaquePath = true, base.opaquePath
+				w.query, w.hasQuery = base.query, base.hasQuery
+				w.hasFrag = true
+				state = stateFragment
+			case base.scheme != "file":
+				state = stateRelative
+				p--
+			default:
+				state = stateFile
+				p--
+			}
+
+		case stateSpecialRelativeOrAuthority:
+			if c == '/' && remainingStarts(p, "/") {
+				state = stateSpecialAuthorityIgnoreSlashes
+				p++
+			} else {
+				state = stateRelative
+				p--
+			}
+
+		case statePathOrAuthority:
+			if c == '/' {
+				state = stateAuthority
+			} else {
+				state = statePath
+				p--
+			}
+
+		case stateRelative:
+			w.scheme = base.scheme
+			if c == '/' || (w.special() && c == '\\') {
+				state = stateRelativeSlash
+			} else {
+				w.username, w.password = base.username, base.password
+				w.host, w.hasHost, w.port = base.host, base.hasHost, base.port
+				w.path = append([]string(nil), base.path...)
+				w.query, w.hasQuery = base.query, base.hasQuery
+				switch c {
+				case '?':
+					w.query, w.hasQuery = "", true
+					state = stateQuery
+				case '#':
+					w.hasFrag = true
+					state = stateFragment
+				case eof:
+				default:
+					w.query, w.hasQuery = "", false
+					w.shortenPath()
+					state = statePath
+					p--
+				}
+			}
+
+		case stateRelativeSlash:
+			if w.special() && (c == '/' || c == '\\') {
+				state = stateSpecialAuthorityIgnoreSlashes
+			} else if c == '/' {
+				state = stateAuthority
+			} else {
+				w.username, w.password = base.username, base.password
+				w.host, w.hasHost, w.port = base.host, base.hasHost, base.port
+				state = statePath
+				p--
+			}
+
+		case stateSpecialAuthoritySlashes:
+			state = stateSpecialAuthorityIgnoreSlashes
+			if c == '/' && remainingStarts(p, "/") {
+				p++
+			} else {
+				p--
+			}
+
+		case stateSpecialAuthorityIgnoreSlashes:
+			if c != '/' && c != '\\' {
+				state = stateAuthority
+				p--
+			}
+
+		case stateAuthority:
+			if c == '@' {
+				if atSignSeen {
+					buf = append([]byte("%40"), buf...)
+				}
+				atSignSeen = true
+				for _, r := range string(buf) {
+					if r == ':' && !passwordSeen {
+						passwordSeen = true
+						continue
+					}
+					if passwordSeen {
+						w.password = string(appendEncoded([]byte(w.password), r, inUserinfoSet))
+					} else {
+						w.username = string(appendEncoded([]byte(w.username), r, inUserinfoSet))
+					}
+				}
+				buf = buf[:0]
+			} else if c == eof || c == '/' || c == '?' || c == '#' || (w.special() && c == '\\') {
+				if atSignSeen && len(buf) == 0 {
+					return nil, errWHATWGFailure
+				}
+				p -= utf8.RuneCount(buf) + 1
+				buf = buf[:0]
+				state = stateHost
+			} else {
+				buf = utf8.AppendRune(buf, c)
+			}
+
+		case stateHost:
+			if c == ':' && !insideBrackets {
+				if len(buf) == 0 {
+					return nil, errWHATWGFailure
+				}
+				host, err := parseWHATWGHost(string(buf), !w.special())
+				if err != nil {
+					return nil, err
+				}
+				w.host, w.hasHost = host, true
+				buf = buf[:0]
+				state = statePort
+			} else if c == eof || c == '/' || c == '?' || c == '#' || (w.special() && c == '\\') {
+				p--
+				if w.special() && len(buf) == 0 {
+					return nil, errWHATWGFailure
+				}
+				host, err := parseWHATWGHost(string(buf), !w.special())
+				if err != nil {
+					return nil, err
+				}
+				w.host, w.hasHost = host, true
+				buf = buf[:0]
+				state = statePathStart
+			} else {
+				if c == '[' {
+					insideBrackets = true
+				} else if c == ']' {
+					insideBrackets = false
+				}
+				buf = utf8.AppendRune(buf, c)
+			}
+
+		case statePort:
+			if isASCIIDigit(c) {
+				buf = append(buf, byte(c))
+			} else if c == eof || c == '/' || c == '?' || c == '#' || (w.special() && c == '\\') {
+				if len(buf) > 0 {
+					port := strings.TrimLeft(string(buf), "0")
+					if port == "" {
+						port = "0"
+					}
+					if n, err := strconv.Atoi(port); err != nil || n > 65535 {
+						return nil, errWHATWGFailure
+					}
+					if def, ok := whatwgSpecialSchemes[w.scheme]; !ok || def != port {
+						w.port = port
+					}
+					buf = buf[:0]
+				}
+				state = statePathStart
+				p--
+			} else {
+				return nil, errWHATWGFailure
+			}
+
+		case stateFile:
+			w.scheme = "file"
+			w.host, w.hasHost = "", true
+			if c == '/' || c == '\\' {
+				state = stateFileSlash
+			} else if base != nil && base.scheme == "file" {
+				w.host, w.hasHost = base.host, base.hasHost
+				w.path = append([]string(nil), base.path...)
+				w.query, w.hasQuery = base.query, base.hasQuery
+				switch c {
+				case '?':
+					w.query, w.hasQuery = "", true
+					state = stateQuery
+				case '#':
+					w.hasFrag = true
+					state = stateFragment
+				case eof:
+				default:
+					w.query, w.hasQuery = "", false
+					if !startsWithWindowsDriveLetter(rs[p:]) {
+						w.shortenPath()
+					} else {
+						w.path = nil
+					}
+					state = statePath
+					p--
+				}
+			} else {
+				state = statePath
+				p--
+			}
+
+		case stateFileSlash:
+			if c == '/' || c == '\\' {
+				state = stateFileHost
+			} else {
+				if base != nil && base.scheme == "file" {
+					w.host, w.hasHost = base.host, base.hasHost
+					if !startsWithWindowsDriveLetter(rs[p:]) && len(base.path) > 0 && isNormalizedWindowsDriveLetter(base.path[0]) {
+						w.path = append(w.path, base.path[0])
+					}
+				}
+				state = statePath
+				p--
+			}
+
+		case stateFileHost:
+			if c == eof || c == '/' || c == '\\' || c == '?' || c == '#' {
+				p--
+				if isWindowsDriveLetter(string(buf)) {
+					// The buffer is not reset, it is used in the path state
+					state = statePath
+				} else if len(buf) == 0 {
+					w.host, w.hasHost = "", true
+					state = statePathStart
+				} else {
+					host, err := parseWHATWGHost(string(buf), !w.special())
+					if err != nil {
+						return nil, err
+					}
+					if host == "localhost" {
+						host = ""
+					}
+					w.host, w.hasHost = host, true
+					buf = buf[:0]
+					state = statePathStart
+				}
+			} else {
+				buf = utf8.AppendRune(buf, c)
+			}
+
+		case statePathStart:
+			if w.special() {
+				state = statePath
+				if c != '/' && c != '\\' {
+					p--
+				}
+			} else if c == '?' {
+				w.query, w.hasQuery = "", true
+				state = stateQuery
+			} else if c == '#' {
+				w.hasFrag = true
+				state = stateFragment
+			} else if c != eof {
+				state = statePath
+				if c != '/' {
+					p--
+				}
+			}
+
+		case statePath:
+			slash := c == '/' || (w.special() && c == '\\')
+			if c == eof || slash || c == '?' || c == '#' {
+				segment := string(buf)
+				if isDoubleDot(segment) {
+					w.shortenPath()
+					if !slash {
+						w.path = append(w.path, "")
+					}
+				} else if isSingleDot(segment) && !slash {
+					w.path = append(w.path, "")
+				} else if !isSingleDot(segment) {
+					if w.scheme == "file" && len(w.path) == 0 && isWindowsDriveLetter(segment) {
+						segment = segment[:1] + ":"
+					}
+					w.path = append(w.path, segment)
+				}
+				buf = buf[:0]
+				if c == '?' {
+					w.query, w.hasQuery = "", true
+					state = stateQuery
+				} else if c == '#' {
+					w.hasFrag = true
+					state = stateFragment
+				}
+			} else {
+				buf = appendEncoded(buf, c, inPathSet)
+			}
+
+		case stateOpaquePath:
+			switch c {
+			case '?':
+				w.query, w.hasQuery = "", true
+				state = stateQuery
+			case '#':
+				w.hasFrag = true
+				state = stateFragment
+			case ' ':
+				if remainingStarts(p, "?") || remainingStarts(p, "#") {
+					w.opaquePath += "%20"
+				} else {
+					w.opaquePath += " "
+				}
+			case eof:
+			default:
+				w.opaquePath = string(appendEncoded([]byte(w.opaquePath), c, inC0ControlSet))
+			}
+
+		case stateQuery:
+			if c == '#' || c == eof {
+				set := inQuerySet
+				if w.special() {
+					set = inSpecialQuerySet
+				}
+				var q []byte
+				for _, r := range string(buf) {
+					q = appendEncoded(q, r, set)
+				}
+				w.query += string(q)
+				buf = buf[:0]
+				if c == '#' {
+					w.hasFrag = true
+					state = stateFragment
+				}
+			} else {
+				buf = utf8.AppendRune(buf, c)
+			}
+
+		case stateFragment:
+			if c != eof {
+				w.fragment = string(appendEncoded([]byte(w.fragment), c, inFragmentSet))
+			}
+		}
+	}
+	return w, nil
+}
+
+// parseWHATWGHost implements the host parser of the URL Standard, and
+// returns the serialized host.
+func parseWHATWGHost(input string, isOpaque bool) (string, error) {
+	if strings.HasPrefix(input, "[") {
+		if !strings.HasSuffix(input, "]") {
+			return "", errWHATWGFailure
+		}
+		addr, err := parseIPv6(input[1 : len(input)-1])
+		if err != nil {
+			return "", err
+		}
+		return "[" + formatIPv6(addr) + "]", nil
+	}
+
+	if isOpaque {
+		if strings.ContainsAny(input, forbiddenHostCodePoints) {
+			return "", errWHATWGFailure
+		}
+		var buf []byte
+		for _, r := range input {
+			buf = appendEncoded(buf, r, inC0ControlSet)
+		}
+		return string(buf), nil
+	}
+
+	domain := strings.ToValidUTF8(percentDecode(input), "�")
+	ascii, err := whatwgIDNA.ToASCII(domain)
+	if err != nil || ascii == "" {
+		return "", errWHATWGFailure
+	}
+	if strings.ContainsAny(ascii, forbiddenHostCodePoints+"%\x7F") || strings.IndexFunc(ascii, func(r rune) bool { return r < 0x20 }) >= 0 {
+		return "", errWHATWGFailure
+	}
+	if endsInANumber(ascii) {
+		addr, err := parseIPv4(ascii)
+		if err != nil {
+			return "", err
+		}
+		return formatIPv4(addr), nil
+	}
+	return ascii, nil
+}
+
+// Code points that are not allowed in hosts. Domains additionally forbid
+// C0 control code points, '%' and U+007F DELETE.
+const forbiddenHostCodePoints = "\x00\t\n\r #/:<>?@[\\]^|"